@@ -1,4 +1,4 @@
 // Package todov1 contains the protobuf definitions for the Todo API.
 //
-//go:generate protoc --go_out=../.. --go_opt=paths=source_relative todo.proto
+//go:generate protoc --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative todo.proto
 package todov1
\ No newline at end of file
@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/yourorg/todo-app/grpcserver"
 	"github.com/yourorg/todo-app/handlers"
 	"github.com/yourorg/todo-app/internal/config"
 	"github.com/yourorg/todo-app/internal/middleware"
+	"github.com/yourorg/todo-app/internal/tenant"
 	"github.com/yourorg/todo-app/services"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -21,14 +29,24 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
-	// Initialize tracing
-	middleware.InitNoopTracer()
+	// Emit structured JSON logs; the stdlib log package is routed through the same handler
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.LogLevel})))
 
-	// Connect to database
-	db, err := gorm.Open(postgres.Open(cfg.GetDatabaseDSN()), &gorm.Config{})
+	// Initialize tracing (no exporter configured: spans are not exported)
+	shutdownTracer := middleware.InitTracer(nil)
+
+	// Connect to database, waiting for it to come up
+	db, err := connectDatabase(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
 
 	// Run migrations
 	if err := services.AutoMigrate(db); err != nil {
@@ -38,21 +56,114 @@ func main() {
 	log.Println("Database migrations completed successfully")
 
 	// Create service
-	todoService := services.NewTodoService(db).Build()
+	todoService := services.NewTodoService(db).
+		WithListAllowlist(cfg.ListSortable, cfg.ListFilterable).
+		WithMinDescriptionLength(cfg.MinDescriptionLength).
+		WithDescriptionLimitMode(cfg.DescriptionLimitMode).
+		WithDefaultListFilter(cfg.DefaultListFilter).
+		WithLockTimeout(cfg.LockTimeout).
+		WithSubtaskCompletionGuard(cfg.RequireSubtasksDone).
+		WithInsertBatchSize(cfg.InsertBatchSize).
+		WithOutbox(cfg.WebhookURL != "").
+		Build()
+
+	// Deliver outbox events to the webhook until shutdown; undelivered events survive restarts
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatchDone := make(chan struct{})
+	if cfg.WebhookURL != "" {
+		deliver := services.WebhookDeliverer(cfg.WebhookURL, &http.Client{Timeout: 10 * time.Second})
+		dispatcher := services.NewOutboxDispatcher(db, deliver).WithMaxAttempts(cfg.WebhookMaxAttempts)
+		go func() {
+			dispatcher.Run(dispatchCtx)
+			close(dispatchDone)
+		}()
+	} else {
+		close(dispatchDone)
+	}
+
+	// Apply error message overrides
+	handlers.SetErrorMessages(cfg.ErrorMessages)
+	if err := handlers.SetTimestampPrecision(cfg.TimestampPrecision); err != nil {
+		log.Fatalf("Invalid TIMESTAMP_PRECISION: %v", err)
+	}
+	if err := handlers.SetQueryParamMode(cfg.QueryParamMode); err != nil {
+		log.Fatalf("Invalid QUERY_PARAM_MODE: %v", err)
+	}
+	handlers.SetMaxBodyBytes(int64(cfg.MaxBodyBytes))
+	if err := handlers.SetSystemNotice(cfg.SystemNotice); err != nil {
+		log.Fatalf("Invalid SYSTEM_NOTICE: %v", err)
+	}
 
 	// Setup routes
 	mux := handlers.SetupRoutes(todoService)
 
 	// Wrap with middleware
-	handler := middleware.Logging(middleware.Tracing(mux))
+	var handler http.Handler = mux
+	handler = middleware.Tracing(middleware.Metrics(handler))
+	if cfg.CacheMaxAge > 0 {
+		handler = middleware.CacheControl(cfg.CacheMaxAge)(handler)
+	}
+	// Outside Tracing and Metrics: its request copy would hide the matched route from them
+	handler = middleware.Timeout(cfg.RequestTimeout)(handler)
+	if cfg.AdminToken != "" {
+		handler = middleware.Admin(cfg.AdminToken)(handler)
+	}
+	// gRPC calls get the same authentication as HTTP through interceptors
+	var grpcInterceptors []grpc.UnaryServerInterceptor
+	switch cfg.AuthMode {
+	case middleware.AuthNone:
+	case middleware.AuthJWT:
+		if cfg.JWTSecret == "" {
+			log.Fatalf("AUTH_MODE=%s requires JWT_SECRET", cfg.AuthMode)
+		}
+		handler = middleware.Auth(cfg.JWTSecret)(handler)
+		grpcInterceptors = append(grpcInterceptors, grpcserver.Auth(cfg.JWTSecret))
+	case middleware.AuthAPIKey:
+		if len(cfg.APIKeys) == 0 {
+			log.Fatalf("AUTH_MODE=%s requires API_KEYS", cfg.AuthMode)
+		}
+		handler = middleware.APIKey(cfg.APIKeys)(handler)
+		grpcInterceptors = append(grpcInterceptors, grpcserver.APIKey(cfg.APIKeys))
+	default:
+		log.Fatalf("Invalid AUTH_MODE %q: must be none, jwt or apikey", cfg.AuthMode)
+	}
+	if cfg.MultiTenant() {
+		tenants := tenant.NewManager(db, services.AutoMigrate)
+		handler = middleware.Tenant(cfg.TenantHeader, cfg.TenantBaseDomain, tenants)(handler)
+		// Outermost, as over HTTP, so authentication runs inside the tenant
+		grpcInterceptors = slices.Insert(grpcInterceptors, 0, grpcserver.Tenant(cfg.TenantHeader, cfg.TenantBaseDomain, tenants))
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		handler = middleware.ConcurrencyLimit(cfg.MaxConcurrentRequests, cfg.ConcurrencyPolicy)(handler)
+	}
+	handler = middleware.MaxHeaderBytes(cfg.MaxHeaderBytes)(handler)
+	if cfg.RateLimitRPS > 0 {
+		handler = middleware.RateLimit(cfg.RateLimitRPS, cfg.RateLimitBurst)(handler)
+	}
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		handler = middleware.CORS(cfg.CORSAllowedOrigins)(handler)
+	}
+	handler = middleware.ProcessingTime(handler)
+	handler = middleware.Logging(handler)
+	handler = middleware.RequestID(handler)
 
-	// Create server
+	// Create server, counting open connections so shutdown can report what it drained
+	var activeConns atomic.Int64
 	server := &http.Server{
-		Addr:         cfg.GetServerAddress(),
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:           cfg.GetServerAddress(),
+		Handler:        handler,
+		ReadTimeout:    15 * time.Second,
+		WriteTimeout:   15 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				activeConns.Add(1)
+			case http.StateHijacked, http.StateClosed:
+				activeConns.Add(-1)
+			}
+		},
 	}
 
 	// Start server in goroutine
@@ -63,6 +174,19 @@ func main() {
 		}
 	}()
 
+	// Start gRPC server sharing the same service and database connection
+	grpcServer := grpcserver.Register(todoService, grpc.ChainUnaryInterceptor(grpcInterceptors...))
+	grpcListener, err := net.Listen("tcp", cfg.GetGRPCAddress())
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+	go func() {
+		log.Printf("gRPC server starting on %s", cfg.GetGRPCAddress())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatalf("gRPC server failed: %v", err)
+		}
+	}()
+
 	// Wait for interrupt signal for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -74,9 +198,61 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Event streams never go idle, so end them first or Shutdown waits out the timeout
+	subscribers := todoService.CloseSubscriptions()
+	slog.Info("Draining connections", "http_connections", activeConns.Load(), "event_subscribers", subscribers)
+
+	// Drain in-flight RPCs alongside HTTP requests
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+		log.Println("gRPC server forced to shutdown")
+	}
+
+	// Stop dispatching; events not yet marked sent are delivered after the next start
+	stopDispatch()
+	<-dispatchDone
+
+	// Nothing uses the database any more
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("Database close failed: %v", err)
+	}
+
+	if err := shutdownTracer(ctx); err != nil {
+		log.Printf("Tracer shutdown failed: %v", err)
+	}
+
 	log.Println("Server stopped")
-}
\ No newline at end of file
+}
+
+// maxConnectDelay caps the wait between database connection attempts
+const maxConnectDelay = 30 * time.Second
+
+// connectDatabase opens the database, retrying with exponential backoff so the API
+// can start before Postgres is ready; it gives up after cfg.DBConnectAttempts attempts
+func connectDatabase(cfg *config.Config) (*gorm.DB, error) {
+	delay := cfg.DBConnectBaseDelay
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(postgres.Open(cfg.GetDatabaseDSN()), &gorm.Config{})
+		if err == nil {
+			return db, nil
+		}
+		if attempt >= cfg.DBConnectAttempts {
+			return nil, fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("Database not reachable, retrying", "attempt", attempt, "max_attempts", cfg.DBConnectAttempts, "retry_in", delay, "error", err)
+		time.Sleep(delay)
+		delay = min(2*delay, maxConnectDelay)
+	}
+}
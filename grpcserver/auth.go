@@ -0,0 +1,55 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yourorg/todo-app/internal/auth"
+	"github.com/yourorg/todo-app/internal/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Auth returns an interceptor that authenticates every call with an HS256 JWT signed with
+// secret ("authorization: Bearer <token>" metadata) and serves it as the token's user,
+// as middleware.Auth does over HTTP. Calls without a valid token get Unauthenticated
+func Auth(secret string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		token, ok := strings.CutPrefix(firstMetadata(ctx, "authorization"), "Bearer ")
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		user, err := auth.ParseToken(strings.TrimSpace(token), []byte(secret), time.Now())
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		}
+		return handler(auth.WithUser(ctx, user), req)
+	}
+}
+
+// firstMetadata returns the first value of the incoming metadata key, or "" without one
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// APIKey returns an interceptor that authenticates every call presenting one of keys in
+// x-api-key metadata, as middleware.APIKey does over HTTP. Calls without a valid key get
+// Unauthenticated; a key names a calling service, so todos are not scoped per user
+func APIKey(keys []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		presented := firstMetadata(ctx, strings.ToLower(middleware.APIKeyHeader))
+		if presented == "" {
+			return nil, status.Error(codes.Unauthenticated, "API key required")
+		}
+		if !middleware.ValidAPIKey(keys, presented) {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		return handler(ctx, req)
+	}
+}
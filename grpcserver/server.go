@@ -0,0 +1,178 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the generated TodoServiceServer on top of services.TodoService
+// Validation and sentinel errors are shared with the REST handlers
+type Server struct {
+	todov1.UnimplementedTodoServiceServer
+	service services.TodoService
+}
+
+// NewServer creates a gRPC TodoService backed by service
+func NewServer(service services.TodoService) *Server {
+	return &Server{service: service}
+}
+
+// Register creates a gRPC server with the TodoService registered
+func Register(service services.TodoService, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	todov1.RegisterTodoServiceServer(server, NewServer(service))
+	return server
+}
+
+// CreateTodo creates a new todo item
+func (s *Server) CreateTodo(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Create(ctx, req)
+	return todo, toStatus(err)
+}
+
+// CreateTodoIfAbsent returns the active todo with the same description or creates one
+func (s *Server) CreateTodoIfAbsent(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.CreateIfAbsentResponse, error) {
+	resp, err := s.service.CreateIfAbsent(ctx, req)
+	return resp, toStatus(err)
+}
+
+// DuplicateTodo copies a todo under a fresh ID
+func (s *Server) DuplicateTodo(ctx context.Context, req *todov1.DuplicateTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Duplicate(ctx, req)
+	return todo, toStatus(err)
+}
+
+// BatchCreateTodos creates several todos atomically
+func (s *Server) BatchCreateTodos(ctx context.Context, req *todov1.BatchCreateTodosRequest) (*todov1.BatchCreateTodosResponse, error) {
+	resp, err := s.service.BatchCreate(ctx, req)
+	return resp, toStatus(err)
+}
+
+// GetTodo retrieves a todo by ID
+func (s *Server) GetTodo(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Get(ctx, req)
+	return todo, toStatus(err)
+}
+
+// ListSubtasks lists a todo's direct subtasks
+func (s *Server) ListSubtasks(ctx context.Context, req *todov1.ListSubtasksRequest) (*todov1.ListSubtasksResponse, error) {
+	resp, err := s.service.ListSubtasks(ctx, req)
+	return resp, toStatus(err)
+}
+
+// ListTodos lists todos with the same filters, sorting and paging as REST
+func (s *Server) ListTodos(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	resp, err := s.service.List(ctx, req)
+	return resp, toStatus(err)
+}
+
+// SearchTodos matches todos by description
+func (s *Server) SearchTodos(ctx context.Context, req *todov1.SearchTodosRequest) (*todov1.SearchTodosResponse, error) {
+	resp, err := s.service.Search(ctx, req)
+	return resp, toStatus(err)
+}
+
+// GetStats returns aggregate counts over active todos
+func (s *Server) GetStats(ctx context.Context, _ *todov1.StatsRequest) (*todov1.StatsResponse, error) {
+	stats, err := s.service.Stats(ctx)
+	return stats, toStatus(err)
+}
+
+// GetCapabilities reports the optional features and List options this server accepts
+func (s *Server) GetCapabilities(ctx context.Context, _ *todov1.CapabilitiesRequest) (*todov1.CapabilitiesResponse, error) {
+	return s.service.Capabilities(ctx), nil
+}
+
+// UpdateTodo applies a partial update to a todo
+func (s *Server) UpdateTodo(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.UpdateTodoResponse, error) {
+	resp, err := s.service.Update(ctx, req)
+	return resp, toStatus(err)
+}
+
+// CompleteAll marks every pending todo completed
+func (s *Server) CompleteAll(ctx context.Context, req *todov1.CompleteAllRequest) (*todov1.CompleteAllResponse, error) {
+	resp, err := s.service.CompleteAll(ctx, req)
+	return resp, toStatus(err)
+}
+
+// DeleteTodo soft-deletes a todo, or describes the impact for dry runs
+func (s *Server) DeleteTodo(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
+	resp, err := s.service.Delete(ctx, req)
+	return resp, toStatus(err)
+}
+
+// RestoreTodo brings a soft-deleted todo back from the trash
+func (s *Server) RestoreTodo(ctx context.Context, req *todov1.RestoreTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Restore(ctx, req)
+	return todo, toStatus(err)
+}
+
+// UndoDelete restores the most recently deleted todo within the undo window
+func (s *Server) UndoDelete(ctx context.Context, _ *todov1.UndoDeleteRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Undo(ctx)
+	return todo, toStatus(err)
+}
+
+// MoveTodo reorders a todo within the manual position order
+func (s *Server) MoveTodo(ctx context.Context, req *todov1.MoveTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Move(ctx, req)
+	return todo, toStatus(err)
+}
+
+// ArchiveTodo hides a todo from the default list
+func (s *Server) ArchiveTodo(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Archive(ctx, req)
+	return todo, toStatus(err)
+}
+
+// UnarchiveTodo returns an archived todo to the default list
+func (s *Server) UnarchiveTodo(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.service.Unarchive(ctx, req)
+	return todo, toStatus(err)
+}
+
+// statusCodes maps service sentinel errors to gRPC codes, mirroring handlers.Errors
+var statusCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{services.ErrServiceUnavailable, codes.Unavailable},
+	{services.ErrTodoNotFound, codes.NotFound},
+	{services.ErrNothingToUndo, codes.NotFound},
+	{services.ErrEmptyDescription, codes.InvalidArgument},
+	{services.ErrDescriptionTooShort, codes.InvalidArgument},
+	{services.ErrInvalidCharacters, codes.InvalidArgument},
+	{services.ErrTodoNotDeleted, codes.FailedPrecondition},
+	{services.ErrTodoLocked, codes.Aborted},
+	{services.ErrPreconditionFailed, codes.Aborted},
+	{services.ErrVersionConflict, codes.Aborted},
+	{services.ErrSubtasksIncomplete, codes.FailedPrecondition},
+	{services.ErrInvalidInput, codes.InvalidArgument},
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+}
+
+// toStatus converts a service error to a gRPC status error
+// Unknown errors become Internal without leaking their details
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, m := range statusCodes {
+		if errors.Is(err, m.err) {
+			// Batch failures name the offending item, e.g. "item 2: todo description cannot be empty"
+			var itemErr *services.BatchItemError
+			if errors.As(err, &itemErr) {
+				return status.Error(m.code, itemErr.Error())
+			}
+			return status.Error(m.code, m.err.Error())
+		}
+	}
+	return status.Error(codes.Internal, "an unexpected error occurred")
+}
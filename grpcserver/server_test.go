@@ -0,0 +1,327 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/internal/tenant"
+	"github.com/yourorg/todo-app/services"
+	"github.com/yourorg/todo-app/testutil"
+)
+
+// setupTest starts the gRPC server over an in-memory listener and returns a connected client
+func setupTest(t *testing.T, opts ...grpc.ServerOption) (todov1.TodoServiceClient, func()) {
+	db, cleanup := testutil.SetupTestDB(t)
+
+	listener := bufconn.Listen(1 << 20)
+	server := Register(services.NewTodoService(db).Build(), opts...)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+
+	return todov1.NewTodoServiceClient(conn), func() {
+		conn.Close()
+		server.Stop()
+		testutil.TruncateTables(db, "todos")
+		cleanup()
+	}
+}
+
+// TestGRPC_CreateGetList tests that gRPC calls round-trip through the shared service
+func TestGRPC_CreateGetList(t *testing.T) {
+	client, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{Description: "Over gRPC", Tags: []string{"Work"}})
+	if err != nil {
+		t.Fatalf("CreateTodo failed: %v", err)
+	}
+
+	got, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetTodo failed: %v", err)
+	}
+	if diff := cmp.Diff(created.Description, got.Description); diff != "" {
+		t.Errorf("Unexpected description (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"work"}, got.Tags); diff != "" {
+		t.Errorf("Unexpected tags (-want +got):\n%s", diff)
+	}
+
+	list, err := client.ListTodos(ctx, &todov1.ListTodosRequest{})
+	if err != nil {
+		t.Fatalf("ListTodos failed: %v", err)
+	}
+	if list.Total != 1 || len(list.Todos) != 1 || list.Todos[0].Id != created.Id {
+		t.Errorf("Expected the created todo to be listed, got %v", list)
+	}
+
+	updated, err := client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{Id: created.Id, Completed: func() *bool { b := true; return &b }()})
+	if err != nil {
+		t.Fatalf("UpdateTodo failed: %v", err)
+	}
+	if !updated.Todo.Completed {
+		t.Error("Expected todo to be completed")
+	}
+
+	stats, err := client.GetStats(ctx, &todov1.StatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	wantStats := &todov1.StatsResponse{Total: 1, Completed: 1, CreatedLast_24H: 1}
+	if diff := cmp.Diff(wantStats, stats, protocmp.Transform()); diff != "" {
+		t.Errorf("Unexpected stats (-want +got):\n%s", diff)
+	}
+}
+
+// TestGRPC_ErrorCodes tests that service sentinel errors map to gRPC status codes
+func TestGRPC_ErrorCodes(t *testing.T) {
+	client, cleanup := setupTest(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	active, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{Description: "Active"})
+	if err != nil {
+		t.Fatalf("CreateTodo failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		call     func() error
+		wantCode codes.Code
+	}{
+		{
+			name: "Empty description",
+			call: func() error {
+				_, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{Description: "   "})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Malformed ID",
+			call: func() error {
+				_, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: "not-a-uuid"})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Missing todo",
+			call: func() error {
+				_, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: "00000000-0000-0000-0000-000000000000"})
+				return err
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "Restore of an active todo",
+			call: func() error {
+				_, err := client.RestoreTodo(ctx, &todov1.RestoreTodoRequest{Id: active.Id})
+				return err
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("Expected code %s, got %s (%v)", tc.wantCode, got, err)
+			}
+		})
+	}
+}
+
+// TestToStatus tests the sentinel error to gRPC code mapping
+func TestToStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{name: "Nil", err: nil, wantCode: codes.OK},
+		{name: "Not found", err: fmt.Errorf("get todo x: %w", services.ErrTodoNotFound), wantCode: codes.NotFound, wantMsg: "todo not found"},
+		{name: "Locked", err: fmt.Errorf("update todo x: %w", services.ErrTodoLocked), wantCode: codes.Aborted, wantMsg: services.ErrTodoLocked.Error()},
+		{name: "Unavailable", err: fmt.Errorf("%w: dial tcp", services.ErrServiceUnavailable), wantCode: codes.Unavailable, wantMsg: "service unavailable"},
+		{name: "Batch item", err: fmt.Errorf("batch create: %w", &services.BatchItemError{Index: 2, Err: services.ErrEmptyDescription}), wantCode: codes.InvalidArgument, wantMsg: "item 2: todo description cannot be empty"},
+		{name: "Deadline", err: fmt.Errorf("list: %w", context.DeadlineExceeded), wantCode: codes.DeadlineExceeded, wantMsg: context.DeadlineExceeded.Error()},
+		{name: "Unknown hides details", err: fmt.Errorf("query: pq: secret table missing"), wantCode: codes.Internal, wantMsg: "an unexpected error occurred"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tc.err))
+			if st.Code() != tc.wantCode || st.Message() != tc.wantMsg {
+				t.Errorf("Expected %s %q, got %s %q", tc.wantCode, tc.wantMsg, st.Code(), st.Message())
+			}
+		})
+	}
+}
+
+// signToken builds an HS256 JWT for claims signed with secret
+func signToken(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims: %v", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TestGRPC_Auth tests that calls need a valid bearer token and only see their user's todos
+func TestGRPC_Auth(t *testing.T) {
+	const secret = "s3cret"
+	client, cleanup := setupTest(t, grpc.ChainUnaryInterceptor(Auth(secret)))
+	defer cleanup()
+
+	alice := signToken(t, secret, map[string]any{"sub": uuid.NewString()})
+	bob := signToken(t, secret, map[string]any{"sub": uuid.NewString()})
+	asUser := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	created, err := client.CreateTodo(asUser(alice), &todov1.CreateTodoRequest{Description: "Alice's"})
+	if err != nil {
+		t.Fatalf("CreateTodo as Alice failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{name: "No credentials", ctx: context.Background(), wantCode: codes.Unauthenticated},
+		{name: "Invalid token", ctx: asUser(signToken(t, "guess", map[string]any{"sub": uuid.NewString()})), wantCode: codes.Unauthenticated},
+		{name: "Other user", ctx: asUser(bob), wantCode: codes.NotFound},
+		{name: "Owner", ctx: asUser(alice), wantCode: codes.OK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.GetTodo(tc.ctx, &todov1.GetTodoRequest{Id: created.Id})
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("Expected code %s, got %s (%v)", tc.wantCode, got, err)
+			}
+		})
+	}
+}
+
+// TestGRPC_APIKey tests that calls need one of the configured keys in x-api-key
+func TestGRPC_APIKey(t *testing.T) {
+	client, cleanup := setupTest(t, grpc.ChainUnaryInterceptor(APIKey([]string{"key-1", "key-2"})))
+	defer cleanup()
+
+	testCases := []struct {
+		name     string
+		key      string
+		wantCode codes.Code
+	}{
+		{name: "No key", wantCode: codes.Unauthenticated},
+		{name: "Unknown key", key: "guess", wantCode: codes.Unauthenticated},
+		{name: "Valid key", key: "key-2", wantCode: codes.OK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.key != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", tc.key)
+			}
+			_, err := client.ListTodos(ctx, &todov1.ListTodosRequest{})
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("Expected code %s, got %s (%v)", tc.wantCode, got, err)
+			}
+		})
+	}
+}
+
+// fakeTenantSessions records the tenants calls were served in
+type fakeTenantSessions struct {
+	mu       sync.Mutex
+	opened   []string
+	released int
+}
+
+func (f *fakeTenantSessions) Open(ctx context.Context, name string) (context.Context, func(), error) {
+	if !tenant.ValidName(name) {
+		return nil, nil, fmt.Errorf("open tenant %q: %w", name, tenant.ErrInvalidName)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opened = append(f.opened, name)
+	return ctx, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.released++
+	}, nil
+}
+
+// TestGRPC_Tenant tests that calls are served in the tenant named by the tenant header's metadata
+func TestGRPC_Tenant(t *testing.T) {
+	testCases := []struct {
+		name       string
+		tenant     string
+		wantCode   codes.Code
+		wantOpened []string
+	}{
+		{name: "No tenant uses the default schema", wantCode: codes.OK},
+		{name: "Header selects tenant", tenant: "ACME", wantCode: codes.OK, wantOpened: []string{"acme"}},
+		{name: "Invalid tenant rejected", tenant: "no spaces", wantCode: codes.InvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions := &fakeTenantSessions{}
+			client, cleanup := setupTest(t, grpc.ChainUnaryInterceptor(Tenant("X-Tenant-ID", "", sessions)))
+			defer cleanup()
+
+			ctx := context.Background()
+			if tc.tenant != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "x-tenant-id", tc.tenant)
+			}
+			_, err := client.ListTodos(ctx, &todov1.ListTodosRequest{})
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("Expected code %s, got %s (%v)", tc.wantCode, got, err)
+			}
+			if diff := cmp.Diff(tc.wantOpened, sessions.opened); diff != "" {
+				t.Errorf("Opened tenants mismatch (-want +got):\n%s", diff)
+			}
+			if sessions.released != len(sessions.opened) {
+				t.Errorf("Expected every opened session released, got %d of %d", sessions.released, len(sessions.opened))
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/yourorg/todo-app/internal/middleware"
+	"github.com/yourorg/todo-app/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Tenant returns an interceptor that serves each call inside its tenant's schema, resolved
+// like middleware.Tenant: from the header's metadata key when set, else from the subdomain
+// of baseDomain in :authority. Calls without a tenant use the default schema; invalid
+// tenants get InvalidArgument
+func Tenant(header, baseDomain string, sessions middleware.TenantSessions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var named string
+		if header != "" {
+			named = firstMetadata(ctx, strings.ToLower(header))
+		}
+		name := middleware.TenantName(named, firstMetadata(ctx, ":authority"), baseDomain)
+		if name == "" {
+			return handler(ctx, req)
+		}
+
+		tenantCtx, release, err := sessions.Open(ctx, name)
+		if errors.Is(err, tenant.ErrInvalidName) {
+			return nil, status.Error(codes.InvalidArgument, "invalid tenant")
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "open tenant session", "tenant", name, "error", err)
+			return nil, status.Error(codes.Internal, "an unexpected error occurred")
+		}
+		defer release()
+
+		return handler(tenantCtx, req)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// DefaultMaxBodyBytes caps the body of JSON writes unless SetMaxBodyBytes says otherwise
+const DefaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytes is the deployment-wide body cap; 0 means DefaultMaxBodyBytes
+var maxBodyBytes atomic.Int64
+
+// SetMaxBodyBytes sets the largest request body JSON writes accept
+// Values below 1 restore DefaultMaxBodyBytes
+func SetMaxBodyBytes(n int64) {
+	if n < 1 {
+		n = 0
+	}
+	maxBodyBytes.Store(n)
+}
+
+// limitBody caps r's body at the configured size, so an oversized body fails while it is
+// being read instead of being buffered in full
+func limitBody(w http.ResponseWriter, r *http.Request) {
+	limit := maxBodyBytes.Load()
+	if limit == 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+}
+
+// decodeBody decodes r's size-limited JSON body into v, responding with an error when it can't
+// A body over the limit gets 413 PAYLOAD_TOO_LARGE, anything else unreadable 400 INVALID_REQUEST
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	limitBody(w, r)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respondBodyError(w, err)
+		return false
+	}
+	return true
+}
+
+// readBody reads r's size-limited body, responding with an error when it can't
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	limitBody(w, r)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondBodyError(w, err)
+		return nil, false
+	}
+	return body, true
+}
+
+// respondBodyError reports a body that failed to read or decode
+func respondBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		RespondWithError(w, Errors.PayloadTooLarge)
+		return
+	}
+	RespondWithError(w, Errors.InvalidRequest)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TimestampPrecisionHeader lets a client choose the precision of emitted timestamps
+const TimestampPrecisionHeader = "X-Timestamp-Precision"
+
+// Supported timestamp precisions
+const (
+	PrecisionFull         = "full"
+	PrecisionSeconds      = "s"
+	PrecisionMilliseconds = "ms"
+)
+
+// defaultPrecision is the deployment-wide precision used when the request doesn't pick one
+var defaultPrecision atomic.Value
+
+// SetTimestampPrecision sets the default precision of timestamps in responses
+// An empty precision restores full precision
+func SetTimestampPrecision(precision string) error {
+	if precision == "" {
+		precision = PrecisionFull
+	}
+	if _, ok := precisionUnit(precision); !ok {
+		return fmt.Errorf("unknown timestamp precision %q", precision)
+	}
+	defaultPrecision.Store(strings.ToLower(precision))
+	return nil
+}
+
+// precisionUnit maps a precision name to the unit timestamps are truncated to
+// Full precision maps to zero, meaning no truncation
+func precisionUnit(precision string) (time.Duration, bool) {
+	switch strings.ToLower(precision) {
+	case PrecisionFull:
+		return 0, true
+	case PrecisionSeconds:
+		return time.Second, true
+	case PrecisionMilliseconds:
+		return time.Millisecond, true
+	}
+	return 0, false
+}
+
+// timestampUnit returns the truncation unit for the request
+// The header wins over the default; unknown header values fall back to the default
+func timestampUnit(r *http.Request) time.Duration {
+	if unit, ok := precisionUnit(r.Header.Get(TimestampPrecisionHeader)); ok {
+		return unit
+	}
+	if precision, ok := defaultPrecision.Load().(string); ok {
+		unit, _ := precisionUnit(precision)
+		return unit
+	}
+	return 0
+}
+
+// encodeJSON writes v as the JSON response body
+// Timestamps inside protobuf messages are truncated to the request's precision
+func encodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok {
+		if unit := timestampUnit(r); unit > 0 {
+			msg = proto.Clone(msg)
+			truncateTimestamps(msg.ProtoReflect(), unit)
+			v = msg
+		}
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// timestampName is the full name of the well-known Timestamp message
+var timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+
+// truncateTimestamps truncates every Timestamp reachable from m to unit, in place
+func truncateTimestamps(m protoreflect.Message, unit time.Duration) {
+	if m.Descriptor().FullName() == timestampName {
+		ts := m.Interface().(*timestamppb.Timestamp)
+		ts.Nanos -= ts.Nanos % int32(unit)
+		return
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				truncateTimestamps(list.Get(i).Message(), unit)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				truncateTimestamps(mv.Message(), unit)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			truncateTimestamps(v.Message(), unit)
+		}
+		return true
+	})
+}
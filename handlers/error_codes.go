@@ -6,23 +6,42 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/yourorg/todo-app/internal/middleware"
 	"github.com/yourorg/todo-app/services"
 )
 
 // ErrorCode represents an HTTP error response
 type ErrorCode struct {
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	HTTPStatus int    `json:"-"`
-	ServiceErr error  `json:"-"` // Maps to service sentinel error
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Index      *int     `json:"index,omitempty"`  // Failing item of a batch request
+	Params     []string `json:"params,omitempty"` // Offending query parameters
+	Max        *int     `json:"max,omitempty"`    // Largest accepted value of the offending parameter
+	Retryable  bool     `json:"retryable,omitempty"`
+	RetryAfter string   `json:"-"` // Retry-After header value, when the wait is known
+	HTTPStatus int      `json:"-"`
+	ServiceErr error    `json:"-"` // Maps to service sentinel error
 }
 
 // Errors is a singleton containing all error codes
 var Errors = struct {
-	InvalidRequest  ErrorCode
-	TodoNotFound    ErrorCode
-	EmptyDescription ErrorCode
-	InternalError   ErrorCode
+	InvalidRequest      ErrorCode
+	PayloadTooLarge     ErrorCode
+	UnknownQueryParam   ErrorCode
+	Forbidden           ErrorCode
+	MethodNotAllowed    ErrorCode
+	TodoNotFound        ErrorCode
+	EmptyDescription    ErrorCode
+	DescriptionTooShort ErrorCode
+	InvalidCharacters   ErrorCode
+	TodoNotDeleted      ErrorCode
+	NothingToUndo       ErrorCode
+	TodoLocked          ErrorCode
+	PreconditionFailed  ErrorCode
+	VersionConflict     ErrorCode
+	SubtasksIncomplete  ErrorCode
+	ServiceUnavailable  ErrorCode
+	InternalError       ErrorCode
 }{
 	InvalidRequest: ErrorCode{
 		Code:       "INVALID_REQUEST",
@@ -30,6 +49,26 @@ var Errors = struct {
 		HTTPStatus: http.StatusBadRequest,
 		ServiceErr: services.ErrInvalidInput,
 	},
+	PayloadTooLarge: ErrorCode{
+		Code:       "PAYLOAD_TOO_LARGE",
+		Message:    "Request body is too large",
+		HTTPStatus: http.StatusRequestEntityTooLarge,
+	},
+	UnknownQueryParam: ErrorCode{
+		Code:       "UNKNOWN_QUERY_PARAMETER",
+		Message:    "Unknown query parameters",
+		HTTPStatus: http.StatusBadRequest,
+	},
+	Forbidden: ErrorCode{
+		Code:       "FORBIDDEN",
+		Message:    "Administrator access required",
+		HTTPStatus: http.StatusForbidden,
+	},
+	MethodNotAllowed: ErrorCode{
+		Code:       "METHOD_NOT_ALLOWED",
+		Message:    "Method not allowed for this resource; see the Allow header",
+		HTTPStatus: http.StatusMethodNotAllowed,
+	},
 	TodoNotFound: ErrorCode{
 		Code:       "TODO_NOT_FOUND",
 		Message:    "Todo not found",
@@ -42,6 +81,63 @@ var Errors = struct {
 		HTTPStatus: http.StatusBadRequest,
 		ServiceErr: services.ErrEmptyDescription,
 	},
+	DescriptionTooShort: ErrorCode{
+		Code:       "DESCRIPTION_TOO_SHORT",
+		Message:    "Todo description is too short",
+		HTTPStatus: http.StatusUnprocessableEntity,
+		ServiceErr: services.ErrDescriptionTooShort,
+	},
+	InvalidCharacters: ErrorCode{
+		Code:       "INVALID_CHARACTERS",
+		Message:    "Todo description contains control characters; only tabs and newlines are allowed",
+		HTTPStatus: http.StatusBadRequest,
+		ServiceErr: services.ErrInvalidCharacters,
+	},
+	TodoNotDeleted: ErrorCode{
+		Code:       "TODO_NOT_DELETED",
+		Message:    "Todo is not in the trash",
+		HTTPStatus: http.StatusConflict,
+		ServiceErr: services.ErrTodoNotDeleted,
+	},
+	NothingToUndo: ErrorCode{
+		Code:       "NOTHING_TO_UNDO",
+		Message:    "No recently deleted todo to restore",
+		HTTPStatus: http.StatusNotFound,
+		ServiceErr: services.ErrNothingToUndo,
+	},
+	TodoLocked: ErrorCode{
+		Code:       "TODO_LOCKED",
+		Message:    "Todo is being updated by another request, please retry",
+		HTTPStatus: http.StatusConflict,
+		Retryable:  true,
+		RetryAfter: "1", // Row locks are held for the length of one write
+		ServiceErr: services.ErrTodoLocked,
+	},
+	PreconditionFailed: ErrorCode{
+		Code:       "PRECONDITION_FAILED",
+		Message:    "Todo was modified since it was read",
+		HTTPStatus: http.StatusPreconditionFailed,
+		ServiceErr: services.ErrPreconditionFailed,
+	},
+	VersionConflict: ErrorCode{
+		Code:       "VERSION_CONFLICT",
+		Message:    "Todo was changed by another update; reload it and retry",
+		HTTPStatus: http.StatusConflict,
+		ServiceErr: services.ErrVersionConflict,
+	},
+	SubtasksIncomplete: ErrorCode{
+		Code:       "SUBTASKS_INCOMPLETE",
+		Message:    "Todo has open subtasks; complete them first or retry with force=true",
+		HTTPStatus: http.StatusConflict,
+		ServiceErr: services.ErrSubtasksIncomplete,
+	},
+	ServiceUnavailable: ErrorCode{
+		Code:       "SERVICE_UNAVAILABLE",
+		Message:    "The service is temporarily unavailable, please retry",
+		HTTPStatus: http.StatusServiceUnavailable,
+		Retryable:  true,
+		ServiceErr: services.ErrServiceUnavailable,
+	},
 	InternalError: ErrorCode{
 		Code:       "INTERNAL_ERROR",
 		Message:    "An unexpected error occurred",
@@ -50,13 +146,28 @@ var Errors = struct {
 	},
 }
 
+// SetErrorMessages replaces the built-in messages for the given error codes
+// Codes without an override keep their default message; nil clears all overrides
+// The overrides are shared with the middleware, so their errors follow them too
+func SetErrorMessages(overrides map[string]string) {
+	middleware.SetErrorMessages(overrides)
+}
+
 // RespondWithError sends an error response
 func RespondWithError(w http.ResponseWriter, errCode ErrorCode) {
+	errCode = withMessageOverride(errCode)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(errCode.HTTPStatus)
 	json.NewEncoder(w).Encode(errCode)
 }
 
+// withMessageOverride applies the deployment's message override for the code, if any
+func withMessageOverride(errCode ErrorCode) ErrorCode {
+	errCode.Message = middleware.ErrorMessage(errCode.Code, errCode.Message)
+	return errCode
+}
+
 // HandleServiceError automatically maps service errors to HTTP responses
 func HandleServiceError(w http.ResponseWriter, err error) {
 	// Check context errors first
@@ -69,20 +180,47 @@ func HandleServiceError(w http.ResponseWriter, err error) {
 		return
 	}
 
+	errCode := errorCodeFor(err)
+
+	// Point batch failures at the offending item
+	var itemErr *services.BatchItemError
+	if errCode.ServiceErr != nil && errors.As(err, &itemErr) {
+		errCode.Index = &itemErr.Index
+	}
+
+	// Transient failures tell clients when to retry if that is known; how long the
+	// database stays unreachable is not, so 503s leave the back-off to the client
+	if errCode.RetryAfter != "" {
+		w.Header().Set("Retry-After", errCode.RetryAfter)
+	}
+
+	RespondWithError(w, errCode)
+}
+
+// errorCodeFor maps a service error to its error code, defaulting to InternalError
+func errorCodeFor(err error) ErrorCode {
 	// Check service error mapping
 	allErrors := []ErrorCode{
+		Errors.ServiceUnavailable,
 		Errors.TodoNotFound,
 		Errors.EmptyDescription,
+		Errors.DescriptionTooShort,
+		Errors.InvalidCharacters,
+		Errors.TodoNotDeleted,
+		Errors.NothingToUndo,
+		Errors.TodoLocked,
+		Errors.PreconditionFailed,
+		Errors.VersionConflict,
+		Errors.SubtasksIncomplete,
 		Errors.InvalidRequest,
 	}
 
 	for _, errCode := range allErrors {
 		if errCode.ServiceErr != nil && errors.Is(err, errCode.ServiceErr) {
-			RespondWithError(w, errCode)
-			return
+			return errCode
 		}
 	}
 
 	// Default to internal error
-	RespondWithError(w, Errors.InternalError)
-}
\ No newline at end of file
+	return Errors.InternalError
+}
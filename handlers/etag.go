@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/internal/auth"
+	"github.com/yourorg/todo-app/internal/tenant"
+)
+
+// etag returns the strong entity tag of a todo, derived from its ID and updated_at
+// updated_at is hashed at the database's microsecond precision so fresh and reloaded todos agree
+func etag(todo *todov1.Todo) string {
+	h := sha256.New()
+	h.Write([]byte(todo.Id))
+	h.Write([]byte{0})
+	if todo.UpdatedAt != nil {
+		h.Write([]byte(strconv.FormatInt(todo.UpdatedAt.AsTime().Truncate(time.Microsecond).UnixMicro(), 10)))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// listETag returns the weak entity tag of a List response: the list generation plus
+// everything else that shapes the result, i.e. the query, tenant, user and admin visibility
+func listETag(r *http.Request, generation string) string {
+	user, _ := auth.UserID(r.Context())
+	h := sha256.New()
+	for _, part := range []string{generation, r.URL.Query().Encode(), tenant.Name(r.Context()), user.String(), strconv.FormatBool(auth.IsAdmin(r.Context()))} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether tag is listed in an If-Match or If-None-Match header
+// "*" matches any tag; weak comparison (If-None-Match) ignores the W/ prefix,
+// strong comparison (If-Match) never matches a weak tag
+func etagMatches(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = candidate[2:]
+		}
+		if candidate == tag || (weak && "W/"+candidate == tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Export formats
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+)
+
+// csvColumns is the header row of a CSV export
+var csvColumns = []string{"id", "description", "completed", "created_at", "updated_at"}
+
+// Export handles GET /api/v1/todos/export?format=csv|json
+// Streams every todo, oldest first, as a dated attachment; csv is the default format
+// The JSON form is {"todos": [...]}, which POST /api/v1/todos:import accepts as is
+func (h *TodoHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ExportCSV
+	}
+
+	var (
+		begin func() error
+		write func(*todov1.Todo) error
+		end   func() error
+	)
+	switch format {
+	case ExportCSV:
+		unit := timestampUnit(r)
+		cw := csv.NewWriter(w)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		begin = func() error { return cw.Write(csvColumns) }
+		write = func(todo *todov1.Todo) error {
+			return cw.Write([]string{
+				todo.Id,
+				todo.Description,
+				strconv.FormatBool(todo.Completed),
+				formatExportTime(todo.CreatedAt, unit),
+				formatExportTime(todo.UpdatedAt, unit),
+			})
+		}
+		end = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case ExportJSON:
+		first := true
+		w.Header().Set("Content-Type", "application/json")
+		begin = func() error {
+			_, err := io.WriteString(w, `{"todos":[`)
+			return err
+		}
+		write = func(todo *todov1.Todo) error {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			return encodeJSON(w, r, todo)
+		}
+		end = func() error {
+			_, err := io.WriteString(w, "]}\n")
+			return err
+		}
+	default:
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	// Nothing is written until the first todo arrives, so a failing query still gets a proper error
+	started := false
+	start := func() error {
+		started = true
+		filename := fmt.Sprintf("todos-%s.%s", time.Now().UTC().Format(time.DateOnly), format)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		return begin()
+	}
+	err := h.service.Export(r.Context(), func(todo *todov1.Todo) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return write(todo)
+	})
+	if err != nil && !started {
+		w.Header().Del("Content-Type")
+		HandleServiceError(w, err)
+		return
+	}
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = end()
+	}
+	if err != nil {
+		// Headers are already sent; all we can do is cut the download short
+		slog.ErrorContext(r.Context(), "export todos", "error", err)
+	}
+}
+
+// Import handles POST /api/v1/todos:import
+// Accepts the JSON export format and responds 201 with the number imported; with
+// ?skip_invalid=true invalid todos are left out and listed under "errors" by index
+func (h *TodoHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var req todov1.ImportTodosRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if r.URL.Query().Get("skip_invalid") == "true" {
+		req.SkipInvalid = true
+	}
+
+	result, err := h.service.Import(r.Context(), &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	failures := make([]ErrorCode, len(result.Skipped))
+	for i, skipped := range result.Skipped {
+		failures[i] = withMessageOverride(errorCodeFor(skipped))
+		failures[i].Index = &skipped.Index
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	encodeJSON(w, r, map[string]interface{}{
+		"imported": result.Imported,
+		"errors":   failures,
+	})
+}
+
+// formatExportTime formats a timestamp as RFC3339 in UTC at the request's precision
+func formatExportTime(ts *timestamppb.Timestamp, unit time.Duration) string {
+	if ts == nil {
+		return ""
+	}
+	t := ts.AsTime().UTC()
+	if unit > 0 {
+		t = t.Truncate(unit)
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds the database ping behind GET /health/ready
+var readyTimeout = 2 * time.Second
+
+// healthCheck handles GET /health and GET /health/live: the process is up and serving
+// HEAD requests get the same status and headers without a body
+func healthCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "ok",
+	})
+}
+
+// Ready handles GET /health/ready
+// Responds 200 when the database answers a ping within readyTimeout, else 503 naming the failure
+func (h *TodoHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	status, database := "ok", "ok"
+	code := http.StatusOK
+	if err := h.service.Ping(ctx); err != nil {
+		status, database = "unavailable", "unreachable: "+err.Error()
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if r.Method == http.MethodHead {
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": map[string]string{"database": database},
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// staticPattern serves the frontend; it matches every GET, so it doesn't count towards Allow on API paths
+const staticPattern = "GET /"
+
+// probeMethods are the methods API routes are registered with, in Allow header order
+var probeMethods = []string{http.MethodDelete, http.MethodGet, http.MethodPatch, http.MethodPost, http.MethodPut}
+
+// methodNotAllowed answers API requests whose path is routed but not for their method
+// with 405, an Allow header listing the path's methods, and the usual error body
+// Without it the static file server's GET / would catch such requests instead
+// API paths no route knows get a plain 404; everything else is served by mux as usual
+func methodNotAllowed(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := mux.Handler(r); pattern != "" && pattern != staticPattern {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		allow := allowedMethods(mux, r)
+		if len(allow) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		RespondWithError(w, Errors.MethodNotAllowed)
+	})
+}
+
+// allowedMethods lists the methods an API route accepts on r's path; GET routes also accept HEAD
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allow []string
+	probe := r.Clone(r.Context())
+	for _, method := range probeMethods {
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern == "" || pattern == staticPattern {
+			continue
+		}
+		allow = append(allow, method)
+		if method == http.MethodGet {
+			allow = append(allow, http.MethodHead)
+		}
+	}
+	return allow
+}
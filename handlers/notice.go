@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourorg/todo-app/internal/auth"
+)
+
+// Notice severities, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// SystemNotice is an operator message clients can display, e.g. upcoming maintenance
+// StartsAt and EndsAt describe the announced window; the notice is withdrawn once EndsAt passes
+type SystemNotice struct {
+	Message  string     `json:"message"`
+	Severity string     `json:"severity"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// currentNotice is the notice served by GET /api/v1/system/notice (nil when there is none)
+// It is held in memory, so each instance keeps its own notice
+var currentNotice atomic.Pointer[SystemNotice]
+
+// SetSystemNotice sets the notice from its JSON form, e.g. {"message": "...", "severity": "warning"}
+// An empty string clears the notice
+func SetSystemNotice(raw string) error {
+	if raw == "" {
+		currentNotice.Store(nil)
+		return nil
+	}
+	var notice SystemNotice
+	if err := json.Unmarshal([]byte(raw), &notice); err != nil {
+		return fmt.Errorf("decode system notice: %w", err)
+	}
+	if err := notice.normalize(); err != nil {
+		return err
+	}
+	currentNotice.Store(&notice)
+	return nil
+}
+
+// normalize trims the notice, defaults its severity to info, and checks it is well formed
+func (n *SystemNotice) normalize() error {
+	n.Message = strings.TrimSpace(n.Message)
+	if n.Message == "" {
+		return errors.New("system notice message is empty")
+	}
+	n.Severity = strings.ToLower(n.Severity)
+	switch n.Severity {
+	case "":
+		n.Severity = SeverityInfo
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("unknown system notice severity %q", n.Severity)
+	}
+	if n.StartsAt != nil && n.EndsAt != nil && !n.EndsAt.After(*n.StartsAt) {
+		return errors.New("system notice ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// getNotice handles GET /api/v1/system/notice
+// Responds 204 when there is no notice, or once the current one has ended
+func getNotice(w http.ResponseWriter, r *http.Request) {
+	notice := currentNotice.Load()
+	if notice == nil || (notice.EndsAt != nil && !time.Now().Before(*notice.EndsAt)) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, notice)
+}
+
+// putNotice handles PUT /api/v1/system/notice, replacing the notice (administrators only)
+func putNotice(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		RespondWithError(w, Errors.Forbidden)
+		return
+	}
+
+	var notice SystemNotice
+	if !decodeBody(w, r, &notice) {
+		return
+	}
+	if err := notice.normalize(); err != nil {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+	currentNotice.Store(&notice)
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, &notice)
+}
+
+// deleteNotice handles DELETE /api/v1/system/notice, clearing the notice (administrators only)
+func deleteNotice(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		RespondWithError(w, Errors.Forbidden)
+		return
+	}
+	currentNotice.Store(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Query parameter modes
+const (
+	QueryParamsLenient = "lenient" // Ignore unknown query parameters (default)
+	QueryParamsStrict  = "strict"  // Reject unknown query parameters with 400
+)
+
+// FeatureStrictQueryParams is reported by GET /api/v1/capabilities in strict mode
+const FeatureStrictQueryParams = "strict_query_params"
+
+// strictQueryParams is set when unknown query parameters are rejected
+var strictQueryParams atomic.Bool
+
+// SetQueryParamMode sets how endpoints treat query parameters they don't recognise
+// An empty mode restores the lenient default
+func SetQueryParamMode(mode string) error {
+	switch strings.ToLower(mode) {
+	case "", QueryParamsLenient:
+		strictQueryParams.Store(false)
+	case QueryParamsStrict:
+		strictQueryParams.Store(true)
+	default:
+		return fmt.Errorf("unknown query parameter mode %q", mode)
+	}
+	return nil
+}
+
+// listQueryParams are the query parameters List understands
+var listQueryParams = map[string]bool{
+	"limit": true, "offset": true, "page_token": true, "page_size": true,
+	"completed": true, "priority": true, "tags": true, "archived": true, "due_before": true,
+	"created_after": true, "created_before": true,
+	"include_deleted": true, "count_deleted": true, "raw": true, "with_age_bucket": true,
+	"sort": true, "sort_by": true, "order": true, "seed": true,
+}
+
+// rejectUnknownParams responds 400 naming any query parameters outside known, in strict mode
+// Returns true when the request was rejected
+func rejectUnknownParams(w http.ResponseWriter, r *http.Request, known map[string]bool) bool {
+	if !strictQueryParams.Load() {
+		return false
+	}
+
+	var unknown []string
+	for name := range r.URL.Query() {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return false
+	}
+
+	slices.Sort(unknown)
+	errCode := Errors.UnknownQueryParam
+	errCode.Params = unknown
+	RespondWithError(w, errCode)
+	return true
+}
@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/yourorg/todo-app/internal/middleware"
 	"github.com/yourorg/todo-app/services"
 )
 
@@ -15,25 +15,51 @@ func SetupRoutes(service services.TodoService) http.Handler {
 
 	// API routes
 	mux.HandleFunc("POST /api/v1/todos", handler.Create)
+	mux.HandleFunc("POST /api/v1/todos:createIfAbsent", handler.CreateIfAbsent)
+	mux.HandleFunc("POST /api/v1/todos:importSnapshot", handler.ImportSnapshot)
+	mux.HandleFunc("POST /api/v1/todos:batchCreate", handler.BatchCreate)
+	mux.HandleFunc("POST /api/v1/todos:import", handler.Import)
+	mux.HandleFunc("POST /api/v1/todos:completeAll", handler.CompleteAll)
+	mux.HandleFunc("POST /api/v1/todos:batchUpdate", handler.BatchUpdate)
+	mux.HandleFunc("POST /api/v1/todos:undo", handler.Undo)
 	mux.HandleFunc("GET /api/v1/todos", handler.List)
+	mux.HandleFunc("GET /api/v1/todos/search", handler.Search)
+	mux.HandleFunc("GET /api/v1/todos/stats", handler.Stats)
+	mux.HandleFunc("GET /api/v1/todos/export", handler.Export)
+	mux.HandleFunc("GET /api/v1/todos/events", handler.Events)
 	mux.HandleFunc("GET /api/v1/todos/{id}", handler.Get)
-	mux.HandleFunc("PUT /api/v1/todos/{id}", handler.Update)
+	mux.HandleFunc("PATCH /api/v1/todos/{id}", handler.Patch)
+	mux.HandleFunc("PUT /api/v1/todos/{id}", handler.Update)                  // Alias of PATCH, kept for existing clients
+	mux.HandleFunc("DELETE /api/v1/todos/completed", handler.DeleteCompleted) // Takes precedence over {id}
 	mux.HandleFunc("DELETE /api/v1/todos/{id}", handler.Delete)
+	mux.HandleFunc("GET /api/v1/todos/{id}/subtasks", handler.ListSubtasks)
+	mux.HandleFunc("GET /api/v1/todos/{id}/snapshot", handler.Snapshot)
+	mux.HandleFunc("POST /api/v1/todos/{id}/restore", handler.Restore)
+	mux.HandleFunc("POST /api/v1/todos/{id}/duplicate", handler.Duplicate)
+	mux.HandleFunc("PUT /api/v1/todos/{id}/position", handler.Move)
+	mux.HandleFunc("POST /api/v1/todos/{id}/archive", handler.Archive)
+	mux.HandleFunc("POST /api/v1/todos/{id}/unarchive", handler.Unarchive)
 
-	// Health check
+	// Capabilities: the optional features and List options this server accepts
+	mux.HandleFunc("GET /api/v1/capabilities", handler.Capabilities)
+
+	// System notice: anyone may read it, administrators set and clear it
+	mux.HandleFunc("GET /api/v1/system/notice", getNotice)
+	mux.HandleFunc("PUT /api/v1/system/notice", putNotice)
+	mux.HandleFunc("DELETE /api/v1/system/notice", deleteNotice)
+
+	// Health checks (GET patterns also match HEAD): live means the process is up,
+	// ready means it can reach the database; /health is kept as an alias of live
 	mux.HandleFunc("GET /health", healthCheck)
+	mux.HandleFunc("GET /health/live", healthCheck)
+	mux.HandleFunc("GET /health/ready", handler.Ready)
+
+	// Prometheus metrics
+	mux.Handle("GET /metrics", middleware.MetricsHandler())
 
 	// Static files
 	fs := http.FileServer(http.Dir("static"))
-	mux.Handle("GET /", fs)
+	mux.Handle(staticPattern, fs)
 
-	return mux
+	return methodNotAllowed(mux)
 }
-
-// healthCheck handles the health check endpoint
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ok",
-	})
-}
\ No newline at end of file
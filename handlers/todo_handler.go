@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	todov1 "github.com/yourorg/todo-app/api/gen/v1"
 	"github.com/yourorg/todo-app/services"
@@ -22,12 +28,15 @@ func NewTodoHandler(service services.TodoService) *TodoHandler {
 }
 
 // Create handles POST /api/v1/todos
+// With an Idempotency-Key header, a retried request returns the todo the first one created
 func (h *TodoHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req todov1.CreateTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondWithError(w, Errors.InvalidRequest)
+	if !decodeBody(w, r, &req) {
 		return
 	}
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = key
+	}
 
 	todo, err := h.service.Create(r.Context(), &req)
 	if err != nil {
@@ -37,33 +46,244 @@ func (h *TodoHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(todo)
+	encodeJSON(w, r, todo)
+}
+
+// BatchCreate handles POST /api/v1/todos:batchCreate
+// Either every todo is created or none are; failures report the offending index
+func (h *TodoHandler) BatchCreate(w http.ResponseWriter, r *http.Request) {
+	var req todov1.BatchCreateTodosRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	resp, err := h.service.BatchCreate(r.Context(), &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	encodeJSON(w, r, resp)
+}
+
+// CompleteAll handles POST /api/v1/todos:completeAll
+func (h *TodoHandler) CompleteAll(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.service.CompleteAll(r.Context(), &todov1.CompleteAllRequest{})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	// Written explicitly so a zero count is still reported
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, map[string]int32{"updated": resp.Updated})
+}
+
+// CreateIfAbsent handles POST /api/v1/todos:createIfAbsent
+// Responds 201 with a new todo, or 200 with the existing active todo of the same description
+func (h *TodoHandler) CreateIfAbsent(w http.ResponseWriter, r *http.Request) {
+	var req todov1.CreateTodoRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	resp, err := h.service.CreateIfAbsent(r.Context(), &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	encodeJSON(w, r, resp.Todo)
+}
+
+// Snapshot handles GET /api/v1/todos/{id}/snapshot
+func (h *TodoHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	snapshot, err := h.service.Snapshot(r.Context(), &todov1.GetTodoRequest{Id: id})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, snapshot)
+}
+
+// DeleteCompleted handles DELETE /api/v1/todos/completed
+// Responds 200 with the number removed rather than 204
+func (h *TodoHandler) DeleteCompleted(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.service.DeleteCompleted(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, map[string]int64{"deleted": deleted})
+}
+
+// Restore handles POST /api/v1/todos/{id}/restore
+func (h *TodoHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	todo, err := h.service.Restore(r.Context(), &todov1.RestoreTodoRequest{Id: id})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, todo)
+}
+
+// Undo handles POST /api/v1/todos:undo
+// Restores the most recently deleted todo; 404 once the undo window has passed
+func (h *TodoHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	todo, err := h.service.Undo(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, todo)
+}
+
+// Duplicate handles POST /api/v1/todos/{id}/duplicate
+// Responds 201 with the copy: incomplete, with " (copy)" appended to the description
+func (h *TodoHandler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	todo, err := h.service.Duplicate(r.Context(), &todov1.DuplicateTodoRequest{Id: id})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	encodeJSON(w, r, todo)
+}
+
+// Move handles PUT /api/v1/todos/{id}/position with a body like {"position": 0}
+// position is the todo's new 0-based index in sort_by=position order; 0 moves it to the
+// top and anything past the end to the bottom
+func (h *TodoHandler) Move(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	var req todov1.MoveTodoRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	req.Id = id
+
+	todo, err := h.service.Move(r.Context(), &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(todo))
+	encodeJSON(w, r, todo)
+}
+
+// Archive handles POST /api/v1/todos/{id}/archive
+func (h *TodoHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, h.service.Archive)
+}
+
+// Unarchive handles POST /api/v1/todos/{id}/unarchive
+func (h *TodoHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, h.service.Unarchive)
+}
+
+// setArchived runs an archive or unarchive call and responds with the resulting todo
+func (h *TodoHandler) setArchived(w http.ResponseWriter, r *http.Request, call func(context.Context, *todov1.ArchiveTodoRequest) (*todov1.Todo, error)) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	todo, err := call(r.Context(), &todov1.ArchiveTodoRequest{Id: id})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, todo)
+}
+
+// ImportSnapshot handles POST /api/v1/todos:importSnapshot
+func (h *TodoHandler) ImportSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req todov1.TodoSnapshot
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	todo, err := h.service.ImportSnapshot(r.Context(), &req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	encodeJSON(w, r, todo)
 }
 
 // List handles GET /api/v1/todos
+// Responds with a weak ETag; a matching If-None-Match gets 304 without running the query
 func (h *TodoHandler) List(w http.ResponseWriter, r *http.Request) {
+	if rejectUnknownParams(w, r, listQueryParams) {
+		return
+	}
+
 	// Parse query parameters
 	query := r.URL.Query()
-	
+
 	req := &todov1.ListTodosRequest{
 		Limit:  20, // default
 		Offset: 0,  // default
 	}
 
-	// Parse limit
-	if limitStr := query.Get("limit"); limitStr != "" {
-		var limit int32
-		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err == nil {
-			req.Limit = limit
-		}
+	// Parse limit, offset and cursor paging (preferred over offset for large lists)
+	// Oversized limits are clamped by the service; the response's limit is the one applied
+	var ok bool
+	if req.Limit, ok = parsePaging(w, query, "limit", req.Limit); !ok {
+		return
 	}
-
-	// Parse offset
-	if offsetStr := query.Get("offset"); offsetStr != "" {
-		var offset int32
-		if _, err := fmt.Sscanf(offsetStr, "%d", &offset); err == nil {
-			req.Offset = offset
-		}
+	if req.Offset, ok = parsePaging(w, query, "offset", req.Offset); !ok {
+		return
+	}
+	req.PageToken = query.Get("page_token")
+	if req.PageSize, ok = parsePaging(w, query, "page_size", 0); !ok {
+		return
 	}
 
 	// Parse completed filter
@@ -77,6 +297,74 @@ func (h *TodoHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse priority filter: a name (LOW, MEDIUM, HIGH), the enum name (PRIORITY_HIGH)
+	// or the number todos carry in JSON (1, 2, 3)
+	if priorityStr := query.Get("priority"); priorityStr != "" {
+		priority, ok := parsePriority(priorityStr)
+		if !ok {
+			RespondWithError(w, Errors.InvalidRequest)
+			return
+		}
+		req.Priority = &priority
+	}
+
+	// Parse due-soon filter; the service rejects values that aren't RFC3339
+	req.DueBefore = query.Get("due_before")
+
+	// Parse creation window; the service checks both bounds and their order
+	req.CreatedAfter = query.Get("created_after")
+	req.CreatedBefore = query.Get("created_before")
+
+	// Parse tags filter (comma-separated, matches any)
+	if tagsStr := query.Get("tags"); tagsStr != "" {
+		req.Tags = strings.Split(tagsStr, ",")
+	}
+
+	// Parse trash visibility
+	req.IncludeDeleted = query.Get("include_deleted") == "true"
+	req.CountDeleted = query.Get("count_deleted") == "true"
+	req.Raw = query.Get("raw") == "true"
+	req.WithAgeBucket = query.Get("with_age_bucket") == "true"
+
+	// Parse archived filter
+	if archivedStr := query.Get("archived"); archivedStr != "" {
+		archived, err := strconv.ParseBool(archivedStr)
+		if err != nil {
+			RespondWithError(w, Errors.InvalidRequest)
+			return
+		}
+		req.Archived = &archived
+	}
+
+	// Parse sort mode, field, direction and seed
+	req.Sort = query.Get("sort")
+	req.SortBy = query.Get("sort_by")
+	req.Order = query.Get("order")
+	if seedStr := query.Get("seed"); seedStr != "" {
+		seed, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			RespondWithError(w, Errors.InvalidRequest)
+			return
+		}
+		req.Seed = &seed
+	}
+
+	// Revalidate against the list generation before running the query
+	var tag string
+	if revalidatable(req) {
+		gen, err := h.service.ListGeneration(r.Context())
+		if err != nil {
+			HandleServiceError(w, err)
+			return
+		}
+		tag = listETag(r, gen.Generation)
+		if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, tag, true) {
+			w.Header().Set("ETag", tag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	response, err := h.service.List(r.Context(), req)
 	if err != nil {
 		HandleServiceError(w, err)
@@ -84,10 +372,53 @@ func (h *TodoHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+	encodeJSON(w, r, response)
+}
+
+// parsePriority reads a priority query value by name, case-insensitively and with or
+// without the PRIORITY_ prefix, or by enum number; unspecified is not a valid filter
+func parsePriority(raw string) (todov1.Priority, bool) {
+	name := strings.ToUpper(raw)
+	if !strings.HasPrefix(name, "PRIORITY_") {
+		name = "PRIORITY_" + name
+	}
+	value, ok := todov1.Priority_value[name]
+	if !ok {
+		n, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return 0, false
+		}
+		if _, ok = todov1.Priority_name[int32(n)]; !ok {
+			return 0, false
+		}
+		value = int32(n)
+	}
+	if value == int32(todov1.Priority_PRIORITY_UNSPECIFIED) {
+		return 0, false
+	}
+	return todov1.Priority(value), true
+}
+
+// revalidatable reports whether a List result only changes when the data does
+// Urgency and age buckets depend on the clock and unseeded shuffles differ on every call
+func revalidatable(req *todov1.ListTodosRequest) bool {
+	if req.WithAgeBucket {
+		return false
+	}
+	switch req.Sort {
+	case services.SortUrgency:
+		return false
+	case services.SortRandom:
+		return req.Seed != nil
+	}
+	return true
 }
 
 // Get handles GET /api/v1/todos/{id}
+// Responds with an ETag; a matching If-None-Match gets 304 Not Modified
 func (h *TodoHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -102,11 +433,140 @@ func (h *TodoHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients revalidating a cached copy get 304 without a body
+	tag := etag(todo)
+	w.Header().Set("ETag", tag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, tag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(todo)
+	encodeJSON(w, r, todo)
+}
+
+// ListSubtasks handles GET /api/v1/todos/{id}/subtasks
+func (h *TodoHandler) ListSubtasks(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	resp, err := h.service.ListSubtasks(r.Context(), &todov1.ListSubtasksRequest{Id: id})
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, resp)
+}
+
+// Search handles GET /api/v1/todos/search?q=
+func (h *TodoHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := &todov1.SearchTodosRequest{
+		Q:    query.Get("q"),
+		Mode: query.Get("mode"),
+	}
+
+	// Parse limit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var limit int32
+		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err == nil {
+			req.Limit = limit
+		}
+	}
+
+	response, err := h.service.Search(r.Context(), req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, response)
+}
+
+// Stats handles GET /api/v1/todos/stats
+func (h *TodoHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.Stats(r.Context())
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, stats)
+}
+
+// Capabilities handles GET /api/v1/capabilities
+// Reports the optional features and List options this server accepts so clients can adapt
+func (h *TodoHandler) Capabilities(w http.ResponseWriter, r *http.Request) {
+	caps := h.service.Capabilities(r.Context())
+	if strictQueryParams.Load() {
+		caps.Features = append(caps.Features, FeatureStrictQueryParams)
+		slices.Sort(caps.Features)
+	}
+	for name := range listQueryParams {
+		caps.ListQueryParams = append(caps.ListQueryParams, name)
+	}
+	slices.Sort(caps.ListQueryParams)
+
+	w.Header().Set("Content-Type", "application/json")
+	encodeJSON(w, r, caps)
+}
+
+// heartbeatInterval is how often an idle event stream sends a comment to keep proxies from timing out
+var heartbeatInterval = 15 * time.Second
+
+// Events handles GET /api/v1/todos/events
+// Streams create, update and delete events as Server-Sent Events until the client disconnects
+func (h *TodoHandler) Events(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout
+	rc.SetWriteDeadline(time.Time{})
+
+	events, cancel := h.service.Subscribe(r.Context())
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: ", event.Type)
+			encodeJSON(w, r, event) // Ends the data line
+			fmt.Fprint(w, "\n")
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
 }
 
 // Update handles PUT /api/v1/todos/{id}
+// Updates that change nothing are not written and carry an X-No-Op: true header
+// If-Match makes the update conditional on the todo's ETag; a stale tag gets 412
+// ?force=true completes a todo even when the subtask guard would refuse it
+// PUT is kept as an alias of PATCH: fields omitted from the body are left unchanged
 func (h *TodoHandler) Update(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -115,24 +575,125 @@ func (h *TodoHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req todov1.UpdateTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	req.Id = id
+	h.update(w, r, &req)
+}
+
+// Patch handles PATCH /api/v1/todos/{id}
+// Only the fields present in the body change. An explicit "completed": null is
+// rejected rather than read as omitted, so a client can't mistake it for a reset
+func (h *TodoHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+	if raw, ok := fields["completed"]; ok && string(raw) == "null" {
+		RespondWithError(w, Errors.InvalidRequest)
+		return
+	}
+
+	var req todov1.UpdateTodoRequest
+	if err := json.Unmarshal(body, &req); err != nil {
 		RespondWithError(w, Errors.InvalidRequest)
 		return
 	}
 
 	req.Id = id
+	h.update(w, r, &req)
+}
+
+// update applies a decoded update request, honoring If-Match, and writes the updated todo
+func (h *TodoHandler) update(w http.ResponseWriter, r *http.Request, req *todov1.UpdateTodoRequest) {
+	id := req.Id
+	if r.URL.Query().Get("force") == "true" {
+		req.Force = true
+	}
+
+	// If-Match pins the update to the version the client last saw; the service re-checks it atomically
+	if match := r.Header.Get("If-Match"); match != "" {
+		current, err := h.service.Get(r.Context(), &todov1.GetTodoRequest{Id: id})
+		if err != nil {
+			HandleServiceError(w, err)
+			return
+		}
+		if !etagMatches(match, etag(current), false) {
+			RespondWithError(w, Errors.PreconditionFailed)
+			return
+		}
+		req.ExpectedUpdatedAt = current.UpdatedAt
+	}
+
+	resp, err := h.service.Update(r.Context(), req)
+	if err != nil {
+		HandleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(resp.Todo))
+	if resp.NoOp {
+		w.Header().Set("X-No-Op", "true")
+	}
+	encodeJSON(w, r, resp.Todo)
+}
 
-	todo, err := h.service.Update(r.Context(), &req)
+// batchFailure describes one ID a batch update could not apply to
+type batchFailure struct {
+	ID string `json:"id"`
+	ErrorCode
+}
+
+// BatchUpdate handles POST /api/v1/todos:batchUpdate
+// Responds 200 with the number updated, an error entry for each ID that was skipped and
+// the next occurrence of each recurring todo it completed
+func (h *TodoHandler) BatchUpdate(w http.ResponseWriter, r *http.Request) {
+	var req todov1.BatchUpdateTodosRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	result, err := h.service.BatchUpdate(r.Context(), &req)
 	if err != nil {
 		HandleServiceError(w, err)
 		return
 	}
 
+	failures := make([]batchFailure, len(result.Failures))
+	for i, failure := range result.Failures {
+		errCode := withMessageOverride(errorCodeFor(failure))
+		errCode.Index = &failure.Index
+		failures[i] = batchFailure{ID: req.Ids[failure.Index], ErrorCode: errCode}
+	}
+	nextOccurrences := result.NextOccurrences
+	if nextOccurrences == nil {
+		nextOccurrences = []*todov1.Todo{}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(todo)
+	encodeJSON(w, r, map[string]interface{}{
+		"updated":          result.Updated,
+		"errors":           failures,
+		"next_occurrences": nextOccurrences,
+	})
 }
 
 // Delete handles DELETE /api/v1/todos/{id}
+// With ?dry_run=true it responds 200 with the would-be-deleted todo and its dependents
 func (h *TodoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -140,12 +701,48 @@ func (h *TodoHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req := &todov1.DeleteTodoRequest{Id: id}
-	_, err := h.service.Delete(r.Context(), req)
+	req := &todov1.DeleteTodoRequest{
+		Id:     id,
+		DryRun: r.URL.Query().Get("dry_run") == "true",
+	}
+	resp, err := h.service.Delete(r.Context(), req)
 	if err != nil {
 		HandleServiceError(w, err)
 		return
 	}
 
+	// Dry runs describe the impact instead of deleting
+	if resp.DryRun {
+		w.Header().Set("Content-Type", "application/json")
+		encodeJSON(w, r, resp)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+// parsePaging parses the paging parameter name, returning def when it is absent
+// A non-numeric or negative value gets a 400 naming the parameter and, for page
+// sizes, the largest one the service applies; it reports false when it responded
+func parsePaging(w http.ResponseWriter, query url.Values, name string, def int32) (int32, bool) {
+	raw := query.Get(name)
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.ParseInt(raw, 10, 32)
+	if err == nil && n >= 0 {
+		return int32(n), true
+	}
+
+	errCode := Errors.InvalidRequest
+	errCode.Params = []string{name}
+	if name == "offset" {
+		errCode.Message = "offset must be a non-negative integer"
+	} else {
+		maxLimit := services.MaxListLimit
+		errCode.Message = fmt.Sprintf("%s must be a non-negative integer; at most %d todos are returned per page", name, maxLimit)
+		errCode.Max = &maxLimit
+	}
+	RespondWithError(w, errCode)
+	return 0, false
+}
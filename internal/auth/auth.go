@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type adminKey struct{}
+
+type userKey struct{}
+
+// WithAdmin returns a context marking the request as made by an administrator
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether the request was authenticated as an administrator
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// WithUser returns a context carrying the authenticated user's ID
+func WithUser(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, userKey{}, id)
+}
+
+// UserID returns the authenticated user's ID; ok is false for unauthenticated requests
+func UserID(ctx context.Context) (id uuid.UUID, ok bool) {
+	id, ok = ctx.Value(userKey{}).(uuid.UUID)
+	return id, ok
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestGetEnvLogLevel tests LOG_LEVEL parsing and the fallback to info
+func TestGetEnvLogLevel(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		want  slog.Level
+	}{
+		{name: "Unset defaults to info", value: "", want: slog.LevelInfo},
+		{name: "Debug", value: "debug", want: slog.LevelDebug},
+		{name: "Info", value: "info", want: slog.LevelInfo},
+		{name: "Warn", value: "warn", want: slog.LevelWarn},
+		{name: "Warning alias", value: "warning", want: slog.LevelWarn},
+		{name: "Error", value: "error", want: slog.LevelError},
+		{name: "Case-insensitive", value: "DEBUG", want: slog.LevelDebug},
+		{name: "Unrecognized falls back to info", value: "verbose", want: slog.LevelInfo},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tc.value)
+
+			if got := Load().LogLevel; got != tc.want {
+				t.Errorf("Expected level %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadCORSAllowedOrigins tests CORS_ALLOWED_ORIGINS list parsing
+func TestLoadCORSAllowedOrigins(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		unset bool
+		want  []string
+	}{
+		{name: "Unset disables CORS", unset: true, want: nil},
+		{name: "Single origin", value: "http://localhost:3000", want: []string{"http://localhost:3000"}},
+		{name: "Trims and skips empties", value: " http://a.example , ,http://b.example", want: []string{"http://a.example", "http://b.example"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CORS_ALLOWED_ORIGINS", tc.value)
+			if tc.unset {
+				os.Unsetenv("CORS_ALLOWED_ORIGINS")
+			}
+
+			if got := Load().CORSAllowedOrigins; !slices.Equal(got, tc.want) {
+				t.Errorf("Expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadRequireSubtasksDone tests REQUIRE_SUBTASKS_DONE boolean parsing
+func TestLoadRequireSubtasksDone(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{name: "Unset defaults to off", value: "", want: false},
+		{name: "True", value: "true", want: true},
+		{name: "Numeric true", value: "1", want: true},
+		{name: "False", value: "false", want: false},
+		{name: "Invalid falls back to off", value: "sometimes", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("REQUIRE_SUBTASKS_DONE", tc.value)
+
+			if got := Load().RequireSubtasksDone; got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadDBPool tests the connection pool settings and their fallback to defaults
+func TestLoadDBPool(t *testing.T) {
+	testCases := []struct {
+		name         string
+		maxOpen      string
+		maxIdle      string
+		maxLifetime  string
+		wantOpen     int
+		wantIdle     int
+		wantLifetime time.Duration
+	}{
+		{name: "Unset uses defaults", wantOpen: 25, wantIdle: 5, wantLifetime: 30 * time.Minute},
+		{name: "Custom values", maxOpen: "50", maxIdle: "10", maxLifetime: "1h30m", wantOpen: 50, wantIdle: 10, wantLifetime: 90 * time.Minute},
+		{name: "Invalid values fall back", maxOpen: "many", maxIdle: "-1", maxLifetime: "30", wantOpen: 25, wantIdle: 5, wantLifetime: 30 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DB_MAX_OPEN_CONNS", tc.maxOpen)
+			t.Setenv("DB_MAX_IDLE_CONNS", tc.maxIdle)
+			t.Setenv("DB_CONN_MAX_LIFETIME", tc.maxLifetime)
+
+			cfg := Load()
+			if cfg.DBMaxOpenConns != tc.wantOpen || cfg.DBMaxIdleConns != tc.wantIdle || cfg.DBConnMaxLifetime != tc.wantLifetime {
+				t.Errorf("Expected %d/%d/%s, got %d/%d/%s", tc.wantOpen, tc.wantIdle, tc.wantLifetime,
+					cfg.DBMaxOpenConns, cfg.DBMaxIdleConns, cfg.DBConnMaxLifetime)
+			}
+		})
+	}
+}
+
+// TestLoadDBConnectRetry tests the startup connection retry settings
+func TestLoadDBConnectRetry(t *testing.T) {
+	testCases := []struct {
+		name         string
+		attempts     string
+		baseDelay    string
+		wantAttempts int
+		wantDelay    time.Duration
+	}{
+		{name: "Unset uses defaults", wantAttempts: 10, wantDelay: time.Second},
+		{name: "Custom values", attempts: "3", baseDelay: "250ms", wantAttempts: 3, wantDelay: 250 * time.Millisecond},
+		{name: "Invalid values fall back", attempts: "0", baseDelay: "soon", wantAttempts: 10, wantDelay: time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("DB_CONNECT_ATTEMPTS", tc.attempts)
+			t.Setenv("DB_CONNECT_BASE_DELAY", tc.baseDelay)
+
+			cfg := Load()
+			if cfg.DBConnectAttempts != tc.wantAttempts || cfg.DBConnectBaseDelay != tc.wantDelay {
+				t.Errorf("Expected %d attempts from %s, got %d from %s", tc.wantAttempts, tc.wantDelay, cfg.DBConnectAttempts, cfg.DBConnectBaseDelay)
+			}
+		})
+	}
+}
+
+// TestLoadAuthMode tests AUTH_MODE parsing and its default from JWT_SECRET
+func TestLoadAuthMode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mode      string
+		jwtSecret string
+		apiKeys   string
+		wantMode  string
+		wantKeys  []string
+	}{
+		{name: "Unset without secret is none", wantMode: "none"},
+		{name: "Unset with secret is jwt", jwtSecret: "s3cret", wantMode: "jwt"},
+		{name: "Explicit none overrides secret", mode: "none", jwtSecret: "s3cret", wantMode: "none"},
+		{name: "API keys", mode: "APIKey", apiKeys: "key-one, key-two", wantMode: "apikey", wantKeys: []string{"key-one", "key-two"}},
+		{name: "Unrecognized is kept for main to reject", mode: "basic", wantMode: "basic"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AUTH_MODE", tc.mode)
+			t.Setenv("JWT_SECRET", tc.jwtSecret)
+			t.Setenv("API_KEYS", tc.apiKeys)
+
+			cfg := Load()
+			if cfg.AuthMode != tc.wantMode {
+				t.Errorf("Expected auth mode %q, got %q", tc.wantMode, cfg.AuthMode)
+			}
+			if !slices.Equal(cfg.APIKeys, tc.wantKeys) {
+				t.Errorf("Expected API keys %q, got %q", tc.wantKeys, cfg.APIKeys)
+			}
+		})
+	}
+}
+
+// TestLoadRequestTimeout tests REQUEST_TIMEOUT parsing and its fallback
+func TestLoadRequestTimeout(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "Unset uses default", want: 10 * time.Second},
+		{name: "Custom value", value: "2500ms", want: 2500 * time.Millisecond},
+		{name: "Invalid value falls back", value: "-1s", want: 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("REQUEST_TIMEOUT", tc.value)
+
+			if got := Load().RequestTimeout; got != tc.want {
+				t.Errorf("Expected request timeout %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+// TestLoadZeroDisables tests that settings where 0 means off or unbounded accept 0
+func TestLoadZeroDisables(t *testing.T) {
+	testCases := []struct {
+		name            string
+		value           string
+		wantLockTimeout time.Duration
+		wantRPS         float64
+		wantConcurrent  int
+		wantCacheMaxAge int
+	}{
+		{name: "Unset uses defaults", wantLockTimeout: time.Second},
+		{name: "Zero accepted", value: "0"},
+		{name: "Custom value", value: "5", wantLockTimeout: 5 * time.Millisecond, wantRPS: 5, wantConcurrent: 5, wantCacheMaxAge: 5},
+		{name: "Negative value falls back", value: "-1", wantLockTimeout: time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LOCK_TIMEOUT_MS", tc.value)
+			t.Setenv("RATE_LIMIT_RPS", tc.value)
+			t.Setenv("MAX_CONCURRENT_REQUESTS", tc.value)
+			t.Setenv("CACHE_MAX_AGE", tc.value)
+
+			cfg := Load()
+			if cfg.LockTimeout != tc.wantLockTimeout {
+				t.Errorf("Expected lock timeout %s, got %s", tc.wantLockTimeout, cfg.LockTimeout)
+			}
+			if cfg.RateLimitRPS != tc.wantRPS {
+				t.Errorf("Expected rate limit %g, got %g", tc.wantRPS, cfg.RateLimitRPS)
+			}
+			if cfg.MaxConcurrentRequests != tc.wantConcurrent {
+				t.Errorf("Expected concurrency limit %d, got %d", tc.wantConcurrent, cfg.MaxConcurrentRequests)
+			}
+			if cfg.CacheMaxAge != tc.wantCacheMaxAge {
+				t.Errorf("Expected cache max age %d, got %d", tc.wantCacheMaxAge, cfg.CacheMaxAge)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/yourorg/todo-app/internal/auth"
+)
+
+// AdminTokenHeader carries the administrator token
+const AdminTokenHeader = "X-Admin-Token"
+
+// Admin middleware marks requests presenting token in X-Admin-Token as administrator requests
+// Other requests are served unprivileged rather than rejected
+func Admin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get(AdminTokenHeader)
+			if token != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+				r = r.WithContext(auth.WithAdmin(r.Context()))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yourorg/todo-app/internal/auth"
+)
+
+// TestAdmin tests that only the configured token grants administrator access
+func TestAdmin(t *testing.T) {
+	testCases := []struct {
+		name      string
+		token     string
+		presented string
+		wantAdmin bool
+	}{
+		{name: "Matching token", token: "s3cret", presented: "s3cret", wantAdmin: true},
+		{name: "Wrong token", token: "s3cret", presented: "guess"},
+		{name: "Missing token", token: "s3cret"},
+		{name: "Unconfigured token never matches", presented: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotAdmin bool
+			handler := Admin(tc.token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAdmin = auth.IsAdmin(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil)
+			if tc.presented != "" {
+				req.Header.Set(AdminTokenHeader, tc.presented)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+			if gotAdmin != tc.wantAdmin {
+				t.Errorf("Expected admin=%v, got %v", tc.wantAdmin, gotAdmin)
+			}
+		})
+	}
+}
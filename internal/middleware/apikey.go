@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries a service's API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKey middleware authenticates service-to-service requests presenting one of keys in X-API-Key
+// Like Auth, requests to /api/v1/todos without a valid key get 401 while other routes stay public
+// A key identifies a calling service rather than a user, so todos are not scoped per user
+func APIKey(keys []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get(APIKeyHeader)
+			if presented == "" {
+				if strings.HasPrefix(r.URL.Path, protectedPrefix) {
+					respondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "API key required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !ValidAPIKey(keys, presented) {
+				respondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid API key")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidAPIKey reports whether presented is one of keys, comparing each in constant time
+func ValidAPIKey(keys []string, presented string) bool {
+	valid := false
+	for _, key := range keys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
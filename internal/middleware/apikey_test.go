@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAPIKey tests that only configured keys reach the todos API
+func TestAPIKey(t *testing.T) {
+	keys := []string{"key-one", "key-two"}
+
+	testCases := []struct {
+		name     string
+		path     string
+		key      string
+		wantCode int
+	}{
+		{name: "First key", path: "/api/v1/todos", key: "key-one", wantCode: http.StatusOK},
+		{name: "Second key", path: "/api/v1/todos/123", key: "key-two", wantCode: http.StatusOK},
+		{name: "Missing key", path: "/api/v1/todos", wantCode: http.StatusUnauthorized},
+		{name: "Unknown key", path: "/api/v1/todos", key: "guess", wantCode: http.StatusUnauthorized},
+		{name: "Key prefix doesn't match", path: "/api/v1/todos", key: "key", wantCode: http.StatusUnauthorized},
+		{name: "Public route without key", path: "/api/v1/capabilities", wantCode: http.StatusOK},
+		{name: "Public route with unknown key", path: "/api/v1/capabilities", key: "guess", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := APIKey(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set(APIKeyHeader, tc.key)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d. Body: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if rr.Code != http.StatusUnauthorized {
+				return
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode error body: %v", err)
+			}
+			if body["code"] != "UNAUTHORIZED" || body["message"] == "" {
+				t.Errorf("Expected an UNAUTHORIZED error body, got %v", body)
+			}
+		})
+	}
+}
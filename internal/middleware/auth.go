@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourorg/todo-app/internal/auth"
+)
+
+// Authentication modes, selecting which middleware guards the todos API
+const (
+	AuthNone   = "none"   // No authentication; todos are shared
+	AuthJWT    = "jwt"    // Bearer JWTs; todos are per user (Auth)
+	AuthAPIKey = "apikey" // X-API-Key for services; todos are shared (APIKey)
+)
+
+// protectedPrefix is the part of the API that requires authentication
+const protectedPrefix = "/api/v1/todos"
+
+// Auth middleware authenticates requests bearing an HS256 JWT signed with secret
+// ("Authorization: Bearer <token>") and serves them as the user named by its sub claim
+// Requests to /api/v1/todos without a valid token get 401; other routes stay public
+func Auth(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			protected := strings.HasPrefix(r.URL.Path, protectedPrefix)
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				if protected {
+					unauthorized(w, "Authentication required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := auth.ParseToken(strings.TrimSpace(token), []byte(secret), time.Now())
+			if err != nil {
+				// A bad token is rejected even on public routes rather than silently ignored
+				unauthorized(w, "Invalid or expired token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// unauthorized responds 401 with a challenge for a bearer token
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="todos"`)
+	respondError(w, http.StatusUnauthorized, "UNAUTHORIZED", message)
+}
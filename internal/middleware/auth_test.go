@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourorg/todo-app/internal/auth"
+)
+
+// signToken builds a JWT with the given header algorithm and claims, HMAC-SHA256 signed with secret
+func signToken(t *testing.T, alg, secret string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims: %v", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TestAuth tests bearer token validation and which routes require it
+func TestAuth(t *testing.T) {
+	const secret = "s3cret"
+	user := uuid.New()
+	now := time.Now()
+
+	testCases := []struct {
+		name     string
+		path     string
+		token    string
+		wantCode int
+		wantUser bool
+	}{
+		{
+			name:     "Valid token",
+			path:     "/api/v1/todos",
+			token:    signToken(t, "HS256", secret, map[string]any{"sub": user.String(), "exp": now.Add(time.Hour).Unix()}),
+			wantCode: http.StatusOK,
+			wantUser: true,
+		},
+		{
+			name:     "Valid token without expiry",
+			path:     "/api/v1/todos/" + uuid.NewString(),
+			token:    signToken(t, "HS256", secret, map[string]any{"sub": user.String()}),
+			wantCode: http.StatusOK,
+			wantUser: true,
+		},
+		{
+			name:     "Missing token on todos",
+			path:     "/api/v1/todos",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Missing token on public route",
+			path:     "/api/v1/capabilities",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Wrong secret",
+			path:     "/api/v1/todos",
+			token:    signToken(t, "HS256", "guess", map[string]any{"sub": user.String()}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Unsigned token",
+			path:     "/api/v1/todos",
+			token:    signToken(t, "none", secret, map[string]any{"sub": user.String()}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Expired token",
+			path:     "/api/v1/todos",
+			token:    signToken(t, "HS256", secret, map[string]any{"sub": user.String(), "exp": now.Add(-time.Minute).Unix()}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Token not valid yet",
+			path:     "/api/v1/todos",
+			token:    signToken(t, "HS256", secret, map[string]any{"sub": user.String(), "nbf": now.Add(time.Hour).Unix()}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Subject is not a user ID",
+			path:     "/api/v1/todos",
+			token:    signToken(t, "HS256", secret, map[string]any{"sub": "alice"}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Malformed token",
+			path:     "/api/v1/todos",
+			token:    "not-a-jwt",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotUser uuid.UUID
+			var gotOK bool
+			handler := Auth(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUser, gotOK = auth.UserID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d. Body: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("Expected a WWW-Authenticate challenge with 401")
+			}
+			if gotOK != tc.wantUser || (tc.wantUser && gotUser != user) {
+				t.Errorf("Expected user %v (set=%v), got %v (set=%v)", user, tc.wantUser, gotUser, gotOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CacheControl middleware marks successful API reads as cacheable for maxAgeSeconds
+// Writes are marked no-store; responses vary on Accept-Language and Authorization
+func CacheControl(maxAgeSeconds int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				w.Header().Set("Cache-Control", "no-store")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(&cacheWriter{ResponseWriter: w, maxAge: maxAgeSeconds}, r)
+		})
+	}
+}
+
+// cacheWriter adds caching headers once the status is known, so errors are never cached
+type cacheWriter struct {
+	http.ResponseWriter
+	maxAge      int
+	wroteHeader bool
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		h := cw.Header()
+		if code == http.StatusOK && h.Get("Cache-Control") == "" {
+			h.Set("Cache-Control", fmt.Sprintf("max-age=%d", cw.maxAge))
+			h.Add("Vary", "Accept-Language")
+			h.Add("Vary", "Authorization")
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush streams
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCacheControl tests caching headers on reads and their absence on writes
+func TestCacheControl(t *testing.T) {
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		status    int
+		wantCache string
+		wantVary  []string
+	}{
+		{
+			name:      "GET list is cacheable",
+			method:    http.MethodGet,
+			path:      "/api/v1/todos",
+			status:    http.StatusOK,
+			wantCache: "max-age=60",
+			wantVary:  []string{"Accept-Language", "Authorization"},
+		},
+		{
+			name:      "GET single todo is cacheable",
+			method:    http.MethodGet,
+			path:      "/api/v1/todos/123",
+			status:    http.StatusOK,
+			wantCache: "max-age=60",
+			wantVary:  []string{"Accept-Language", "Authorization"},
+		},
+		{
+			name:      "GET error is not cached",
+			method:    http.MethodGet,
+			path:      "/api/v1/todos/123",
+			status:    http.StatusNotFound,
+			wantCache: "",
+		},
+		{
+			name:      "POST is not cacheable",
+			method:    http.MethodPost,
+			path:      "/api/v1/todos",
+			status:    http.StatusCreated,
+			wantCache: "no-store",
+		},
+		{
+			name:      "Non-API paths are untouched",
+			method:    http.MethodGet,
+			path:      "/health",
+			status:    http.StatusOK,
+			wantCache: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte("{}"))
+			})
+			handler := CacheControl(60)(next)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if got := rr.Header().Get("Cache-Control"); got != tc.wantCache {
+				t.Errorf("Expected Cache-Control %q, got %q", tc.wantCache, got)
+			}
+			if diff := cmp.Diff(tc.wantVary, rr.Header().Values("Vary")); diff != "" {
+				t.Errorf("Vary mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
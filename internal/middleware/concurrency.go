@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Concurrency limit policies for excess requests
+const (
+	ConcurrencyReject = "reject" // Respond 429 immediately
+	ConcurrencyQueue  = "queue"  // Wait for a free slot until the client gives up
+)
+
+// timeNow is the clock used for latency tracking; tests replace it
+var timeNow = time.Now
+
+// ConcurrencyLimit middleware caps the number of in-flight requests at max
+// Excess requests are rejected or queued according to policy
+// Retry-After is the moving average request duration, i.e. how soon a slot is expected to free up
+func ConcurrencyLimit(max int, policy string) func(http.Handler) http.Handler {
+	slots := make(chan struct{}, max)
+	latency := &latencyTracker{}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				if policy != ConcurrencyQueue {
+					w.Header().Set("Retry-After", retryAfter(latency.average()))
+					respondError(w, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "Server is busy, retry later")
+					return
+				}
+
+				// Queue until a slot frees up or the client disconnects
+				select {
+				case slots <- struct{}{}:
+				case <-r.Context().Done():
+					w.Header().Set("Retry-After", retryAfter(latency.average()))
+					respondError(w, http.StatusServiceUnavailable, "SERVER_BUSY", "Server is busy, retry later")
+					return
+				}
+			}
+			defer func() { <-slots }()
+
+			start := timeNow()
+			defer func() { latency.observe(timeNow().Sub(start)) }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// latencyTracker keeps an exponentially weighted moving average of request durations
+type latencyTracker struct {
+	mu  sync.Mutex
+	avg time.Duration
+}
+
+func (l *latencyTracker) observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.avg == 0 {
+		l.avg = d
+		return
+	}
+	l.avg += (d - l.avg) / 8
+}
+
+func (l *latencyTracker) average() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.avg
+}
+
+// retryAfter formats a wait as whole seconds for the Retry-After header, rounding up to at least 1
+func retryAfter(wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrencyLimit tests that requests beyond the cap are rejected or queued
+func TestConcurrencyLimit(t *testing.T) {
+	testCases := []struct {
+		name           string
+		policy         string
+		ctxTimeout     time.Duration
+		wantCode       int
+		wantRetryAfter bool
+	}{
+		{
+			name:           "Reject policy returns 429 when at capacity",
+			policy:         ConcurrencyReject,
+			ctxTimeout:     time.Second,
+			wantCode:       http.StatusTooManyRequests,
+			wantRetryAfter: true,
+		},
+		{
+			name:       "Queue policy waits for a free slot",
+			policy:     ConcurrencyQueue,
+			ctxTimeout: time.Second,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:           "Queue policy gives up when the client does",
+			policy:         ConcurrencyQueue,
+			ctxTimeout:     10 * time.Millisecond,
+			wantCode:       http.StatusServiceUnavailable,
+			wantRetryAfter: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					started <- struct{}{}
+					<-release
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := ConcurrencyLimit(1, tc.policy)(next)
+
+			// Occupy the only slot
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+			}()
+			<-started
+
+			// Free the slot shortly after the second request is issued
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				close(release)
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.ctxTimeout)
+			defer cancel()
+			req := httptest.NewRequest(http.MethodGet, "/fast", nil).WithContext(ctx)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			wg.Wait()
+
+			if rr.Code != tc.wantCode {
+				t.Errorf("Expected status %d, got %d", tc.wantCode, rr.Code)
+			}
+			if got := rr.Header().Get("Retry-After") != ""; got != tc.wantRetryAfter {
+				t.Errorf("Expected Retry-After present=%v, got %q", tc.wantRetryAfter, rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+// TestConcurrencyLimit_RetryAfter tests that Retry-After reflects how long requests actually take
+func TestConcurrencyLimit_RetryAfter(t *testing.T) {
+	testCases := []struct {
+		name      string
+		durations []time.Duration
+		want      string
+	}{
+		{
+			name:      "Defaults to one second before any request completes",
+			durations: nil,
+			want:      "1",
+		},
+		{
+			name:      "Sub-second requests round up to one second",
+			durations: []time.Duration{200 * time.Millisecond},
+			want:      "1",
+		},
+		{
+			name:      "Slow requests lengthen the wait",
+			durations: []time.Duration{2500 * time.Millisecond},
+			want:      "3",
+		},
+		{
+			name:      "Moving average smooths a single outlier",
+			durations: []time.Duration{time.Second, 9 * time.Second},
+			want:      "2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Each request advances a fake clock by its scripted duration
+			var mu sync.Mutex
+			clock := time.Unix(0, 0)
+			timeNow = func() time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				return clock
+			}
+			defer func() { timeNow = time.Now }()
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					close(started)
+					<-release
+					return
+				}
+				d, _ := time.ParseDuration(r.URL.Query().Get("d"))
+				mu.Lock()
+				clock = clock.Add(d)
+				mu.Unlock()
+			})
+			handler := ConcurrencyLimit(1, ConcurrencyReject)(next)
+
+			for _, d := range tc.durations {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/work?d="+d.String(), nil))
+			}
+
+			// Occupy the only slot, then overflow it
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+			}()
+			<-started
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/work", nil))
+			close(release)
+			<-done
+
+			if rr.Code != http.StatusTooManyRequests {
+				t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tc.want {
+				t.Errorf("Expected Retry-After %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestCORS tests origin allowlisting and preflight handling
+func TestCORS(t *testing.T) {
+	testCases := []struct {
+		name          string
+		allowed       []string
+		method        string
+		origin        string
+		preflight     bool
+		wantCode      int
+		wantAllow     string
+		wantMethods   bool
+		wantReachNext bool
+	}{
+		{
+			name:          "Allowed origin",
+			allowed:       []string{"http://localhost:3000"},
+			method:        http.MethodGet,
+			origin:        "http://localhost:3000",
+			wantCode:      http.StatusOK,
+			wantAllow:     "http://localhost:3000",
+			wantReachNext: true,
+		},
+		{
+			name:          "Disallowed origin gets no allow header",
+			allowed:       []string{"http://localhost:3000"},
+			method:        http.MethodGet,
+			origin:        "http://evil.example",
+			wantCode:      http.StatusOK,
+			wantReachNext: true,
+		},
+		{
+			name:          "Wildcard allows any origin",
+			allowed:       []string{"*"},
+			method:        http.MethodPost,
+			origin:        "http://anywhere.example",
+			wantCode:      http.StatusOK,
+			wantAllow:     "http://anywhere.example",
+			wantReachNext: true,
+		},
+		{
+			name:          "Same-origin request untouched",
+			allowed:       []string{"http://localhost:3000"},
+			method:        http.MethodGet,
+			wantCode:      http.StatusOK,
+			wantReachNext: true,
+		},
+		{
+			name:        "Preflight from allowed origin",
+			allowed:     []string{"http://localhost:3000"},
+			method:      http.MethodOptions,
+			origin:      "http://localhost:3000",
+			preflight:   true,
+			wantCode:    http.StatusNoContent,
+			wantAllow:   "http://localhost:3000",
+			wantMethods: true,
+		},
+		{
+			name:          "Preflight from disallowed origin",
+			allowed:       []string{"http://localhost:3000"},
+			method:        http.MethodOptions,
+			origin:        "http://evil.example",
+			preflight:     true,
+			wantCode:      http.StatusOK,
+			wantReachNext: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reached := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			})
+			handler := CORS(tc.allowed)(next)
+
+			req := httptest.NewRequest(tc.method, "/api/v1/todos", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+				req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Errorf("Expected status %d, got %d", tc.wantCode, rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tc.wantAllow {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tc.wantAllow, got)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Methods") != ""; got != tc.wantMethods {
+				t.Errorf("Expected Access-Control-Allow-Methods present=%v, got %q", tc.wantMethods, rr.Header().Get("Access-Control-Allow-Methods"))
+			}
+			if tc.wantMethods && rr.Header().Get("Access-Control-Allow-Headers") != "Content-Type" {
+				t.Errorf("Expected requested headers to be allowed, got %q", rr.Header().Get("Access-Control-Allow-Headers"))
+			}
+			if reached != tc.wantReachNext {
+				t.Errorf("Expected next handler reached=%v, got %v", tc.wantReachNext, reached)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// messageOverrides holds deployment-specific error messages keyed by error code
+var messageOverrides atomic.Pointer[map[string]string]
+
+// SetErrorMessages replaces the built-in messages for the given error codes, both in
+// middleware responses and in handlers.RespondWithError
+// Codes without an override keep their default message; nil clears all overrides
+func SetErrorMessages(overrides map[string]string) {
+	messageOverrides.Store(&overrides)
+}
+
+// ErrorMessage returns the deployment's message for code, or message when it has none
+func ErrorMessage(code, message string) string {
+	if overrides := messageOverrides.Load(); overrides != nil {
+		if msg, ok := (*overrides)[code]; ok {
+			return msg
+		}
+	}
+	return message
+}
+
+// respondError writes an error body with the same shape as handlers.ErrorCode
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": ErrorMessage(code, message),
+	})
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestRespondError_MessageOverrides tests that middleware errors use the deployment's message overrides
+func TestRespondError_MessageOverrides(t *testing.T) {
+	testCases := []struct {
+		name        string
+		overrides   map[string]string
+		wantMessage string
+	}{
+		{
+			name:        "Default message",
+			wantMessage: "Too many requests, retry later",
+		},
+		{
+			name:        "Overridden message",
+			overrides:   map[string]string{"RATE_LIMITED": "Slow down"},
+			wantMessage: "Slow down",
+		},
+		{
+			name:        "Override for another code",
+			overrides:   map[string]string{"UNAUTHORIZED": "Sign in first"},
+			wantMessage: "Too many requests, retry later",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			SetErrorMessages(tc.overrides)
+			defer SetErrorMessages(nil)
+
+			handler := RateLimit(1, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			var rr *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				rr = httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil))
+			}
+			if rr.Code != http.StatusTooManyRequests {
+				t.Fatalf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+			}
+
+			var got map[string]string
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("Failed to decode error body: %v", err)
+			}
+			want := map[string]string{"code": "RATE_LIMITED", "message": tc.wantMessage}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Error body mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// MaxHeaderBytes middleware rejects requests whose combined header size exceeds limit
+// Responds with 431 Request Header Fields Too Large
+func MaxHeaderBytes(limit int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if headerSize(r) > limit {
+				respondError(w, http.StatusRequestHeaderFieldsTooLarge, "HEADERS_TOO_LARGE", "Request headers are too large")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerSize approximates the wire size of the request headers ("Name: value\r\n")
+func headerSize(r *http.Request) int {
+	size := 0
+	for name, values := range r.Header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+		}
+	}
+	return size
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMaxHeaderBytes tests rejection of oversized header sets
+func TestMaxHeaderBytes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		wantCode int
+	}{
+		{
+			name:     "Headers within limit",
+			headers:  map[string]string{"X-Small": "ok"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Single oversized header",
+			headers:  map[string]string{"X-Large": strings.Repeat("a", 2048)},
+			wantCode: http.StatusRequestHeaderFieldsTooLarge,
+		},
+		{
+			name: "Many headers exceeding limit together",
+			headers: map[string]string{
+				"X-Part-1": strings.Repeat("a", 400),
+				"X-Part-2": strings.Repeat("b", 400),
+				"X-Part-3": strings.Repeat("c", 400),
+			},
+			wantCode: http.StatusRequestHeaderFieldsTooLarge,
+		},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := MaxHeaderBytes(1024)(next)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Errorf("Expected status %d, got %d", tc.wantCode, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestLogging tests that requests are logged as structured JSON records
+func TestLogging(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestID     string
+		status        int
+		wantGenerated bool
+	}{
+		{
+			name:      "Logs incoming request ID",
+			requestID: "req-123",
+			status:    http.StatusCreated,
+		},
+		{
+			name:          "Generates request ID when absent",
+			status:        http.StatusNotFound,
+			wantGenerated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			prev := slog.Default()
+			slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+			defer slog.SetDefault(prev)
+
+			handler := RequestID(Logging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			})))
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/todos", nil)
+			if tc.requestID != "" {
+				req.Header.Set(RequestIDHeader, tc.requestID)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var record struct {
+				Level     string  `json:"level"`
+				Method    string  `json:"method"`
+				Path      string  `json:"path"`
+				Status    int     `json:"status"`
+				Duration  float64 `json:"duration"`
+				RequestID string  `json:"request_id"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("Expected a JSON log record, got %q: %v", buf.String(), err)
+			}
+
+			if record.Level != "INFO" || record.Method != http.MethodPost || record.Path != "/api/v1/todos" || record.Status != tc.status {
+				t.Errorf("Unexpected log record: %s", buf.String())
+			}
+			if record.RequestID != rr.Header().Get(RequestIDHeader) {
+				t.Errorf("Expected logged request ID to match response header %q, got %q", rr.Header().Get(RequestIDHeader), record.RequestID)
+			}
+			if tc.wantGenerated {
+				if record.RequestID == "" {
+					t.Error("Expected a generated request ID")
+				}
+			} else if record.RequestID != tc.requestID {
+				t.Errorf("Expected request ID %q, got %q", tc.requestID, record.RequestID)
+			}
+		})
+	}
+}
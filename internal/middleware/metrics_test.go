@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMetrics tests request counters labeled by method, route pattern and status
+func TestMetrics(t *testing.T) {
+	var inFlightDuringRequest float64
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/todos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inFlightDuringRequest = testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(http.MethodGet))
+	})
+	mux.HandleFunc("POST /api/v1/todos", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	handler := Metrics(mux)
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		wantRoute string
+		wantCode  string
+	}{
+		{
+			name:      "Labeled by route pattern, not raw path",
+			method:    http.MethodGet,
+			path:      "/api/v1/todos/123",
+			wantRoute: "GET /api/v1/todos/{id}",
+			wantCode:  "200",
+		},
+		{
+			name:      "Records status from WriteHeader",
+			method:    http.MethodPost,
+			path:      "/api/v1/todos",
+			wantRoute: "POST /api/v1/todos",
+			wantCode:  "201",
+		},
+		{
+			name:      "Unmatched paths share one label",
+			method:    http.MethodGet,
+			path:      "/does/not/exist",
+			wantRoute: unmatchedRoute,
+			wantCode:  "404",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := httpRequestsTotal.WithLabelValues(tc.method, tc.wantRoute, tc.wantCode)
+			before := testutil.ToFloat64(counter)
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("Expected counter to increase by 1, got %v", got)
+			}
+			if got := testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(tc.method)); got != 0 {
+				t.Errorf("Expected no in-flight requests afterwards, got %v", got)
+			}
+		})
+	}
+
+	if inFlightDuringRequest != 1 {
+		t.Errorf("Expected 1 in-flight request while serving, got %v", inFlightDuringRequest)
+	}
+	if got := testutil.CollectAndCount(httpRequestDuration); got != len(testCases) {
+		t.Errorf("Expected %d duration series, got %d", len(testCases), got)
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitSweepInterval is how often idle client buckets are garbage-collected
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit middleware applies a token bucket of rps requests per second, with bursts
+// of up to burst, to each client IP. Excess requests get 429 with Retry-After set to
+// when the next token is due
+func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	limiter := newIPRateLimiter(rate.Limit(rps), burst)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if wait, ok := limiter.allow(clientIP(r)); !ok {
+				w.Header().Set("Retry-After", retryAfter(wait))
+				respondError(w, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests, retry later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ipRateLimiter holds one token bucket per client IP
+// Buckets idle long enough to have refilled are dropped, since a fresh bucket is equivalent
+type ipRateLimiter struct {
+	limit rate.Limit
+	burst int
+	idle  time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*rateClient
+	lastSweep time.Time
+}
+
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newIPRateLimiter(limit rate.Limit, burst int) *ipRateLimiter {
+	// Time for an empty bucket to refill, with a floor so slow limits still get swept
+	idle := time.Duration(float64(burst) / float64(limit) * float64(time.Second))
+	if idle < rateLimitSweepInterval {
+		idle = rateLimitSweepInterval
+	}
+	return &ipRateLimiter{
+		limit:     limit,
+		burst:     burst,
+		idle:      idle,
+		clients:   make(map[string]*rateClient),
+		lastSweep: timeNow(),
+	}
+}
+
+// allow takes a token for ip, or reports how long until one is available
+func (l *ipRateLimiter) allow(ip string) (time.Duration, bool) {
+	now := timeNow()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &rateClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+
+	res := c.limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return rateLimitSweepInterval, false
+	}
+	if wait := res.DelayFrom(now); wait > 0 {
+		res.CancelAt(now)
+		return wait, false
+	}
+	return 0, true
+}
+
+// sweep drops buckets that have been idle for longer than a full refill
+func (l *ipRateLimiter) sweep(now time.Time) {
+	for ip, c := range l.clients {
+		if now.Sub(c.lastSeen) > l.idle {
+			delete(l.clients, ip)
+		}
+	}
+	l.lastSweep = now
+}
+
+// clientIP returns the originating client address, preferring the last X-Forwarded-For hop
+// That hop is the one appended by the proxy in front of us; earlier hops come from the
+// client and could be made up to get a fresh bucket
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := forwarded[len(forwarded)-1]
+		if i := strings.LastIndex(hops, ","); i >= 0 {
+			hops = hops[i+1:]
+		}
+		if ip := strings.TrimSpace(hops); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
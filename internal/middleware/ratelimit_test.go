@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRateLimit tests per-IP token buckets, 429 responses and Retry-After
+func TestRateLimit(t *testing.T) {
+	clock := time.Unix(0, 0)
+	timeNow = func() time.Time { return clock }
+	defer func() { timeNow = time.Now }()
+
+	handler := RateLimit(1, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(remoteAddr, forwarded string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil)
+		req.RemoteAddr = remoteAddr
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		remoteAddr     string
+		forwarded      string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{name: "First request within burst", remoteAddr: "10.0.0.1:1234", wantCode: http.StatusOK},
+		{name: "Second request within burst", remoteAddr: "10.0.0.1:5678", wantCode: http.StatusOK},
+		{name: "Burst exhausted", remoteAddr: "10.0.0.1:1234", wantCode: http.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "Other IP has its own bucket", remoteAddr: "10.0.0.2:1234", wantCode: http.StatusOK},
+		{name: "Forwarded client has its own bucket", remoteAddr: "10.0.0.1:1234", forwarded: "203.0.113.7", wantCode: http.StatusOK},
+		{name: "Spoofed earlier hop shares the bucket", remoteAddr: "10.0.0.1:1234", forwarded: "198.51.100.1, 203.0.113.7", wantCode: http.StatusOK},
+		{name: "Another spoofed hop is still limited", remoteAddr: "10.0.0.1:1234", forwarded: "198.51.100.2, 203.0.113.7", wantCode: http.StatusTooManyRequests, wantRetryAfter: "1"},
+		{name: "Token refills after a second", advance: time.Second, remoteAddr: "10.0.0.1:1234", wantCode: http.StatusOK},
+		{name: "Refilled token used up", remoteAddr: "10.0.0.1:1234", wantCode: http.StatusTooManyRequests, wantRetryAfter: "1"},
+	}
+
+	for _, step := range steps {
+		clock = clock.Add(step.advance)
+		rr := do(step.remoteAddr, step.forwarded)
+		if rr.Code != step.wantCode {
+			t.Errorf("%s: expected status %d, got %d", step.name, step.wantCode, rr.Code)
+		}
+		if got := rr.Header().Get("Retry-After"); got != step.wantRetryAfter {
+			t.Errorf("%s: expected Retry-After %q, got %q", step.name, step.wantRetryAfter, got)
+		}
+	}
+}
+
+// TestRateLimit_Sweep tests that idle client buckets are garbage-collected
+func TestRateLimit_Sweep(t *testing.T) {
+	clock := time.Unix(0, 0)
+	timeNow = func() time.Time { return clock }
+	defer func() { timeNow = time.Now }()
+
+	limiter := newIPRateLimiter(10, 5)
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		limiter.allow(ip)
+	}
+
+	// One client stays active while the others go idle past the sweep interval
+	clock = clock.Add(rateLimitSweepInterval / 2)
+	limiter.allow("10.0.0.1")
+	clock = clock.Add(rateLimitSweepInterval)
+	limiter.allow("10.0.0.4")
+
+	if len(limiter.clients) != 2 {
+		t.Errorf("Expected idle buckets to be swept leaving 2 clients, got %d", len(limiter.clients))
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.4"} {
+		if _, ok := limiter.clients[ip]; !ok {
+			t.Errorf("Expected active client %s to keep its bucket", ip)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestRequestID tests request ID reuse, generation, context propagation and echoing
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		incoming string
+		wantID   string
+	}{
+		{
+			name:     "Reuses incoming header",
+			incoming: "abc-123",
+			wantID:   "abc-123",
+		},
+		{
+			name: "Generates UUID when absent",
+		},
+		{
+			name:     "Replaces oversized header",
+			incoming: strings.Repeat("x", maxRequestIDLength+1),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctxID string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			gotID := rr.Header().Get(RequestIDHeader)
+			if tc.wantID != "" {
+				if gotID != tc.wantID {
+					t.Errorf("Expected request ID %q, got %q", tc.wantID, gotID)
+				}
+			} else if _, err := uuid.Parse(gotID); err != nil {
+				t.Errorf("Expected a generated UUID, got %q", gotID)
+			}
+			if ctxID != gotID {
+				t.Errorf("Expected context ID %q to match response header %q", ctxID, gotID)
+			}
+		})
+	}
+}
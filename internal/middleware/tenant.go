@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/yourorg/todo-app/internal/tenant"
+)
+
+// TenantSessions opens a per-request database scope for a tenant
+// *tenant.Manager is the production implementation
+type TenantSessions interface {
+	Open(ctx context.Context, name string) (context.Context, func(), error)
+}
+
+// Tenant middleware resolves the tenant from the header (when set) or from the
+// subdomain of baseDomain, then serves the request inside that tenant's schema
+// Requests without a tenant use the default schema; invalid tenants get 400
+func Tenant(header, baseDomain string, sessions TenantSessions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := resolveTenant(r, header, baseDomain)
+			if name == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, release, err := sessions.Open(r.Context(), name)
+			if errors.Is(err, tenant.ErrInvalidName) {
+				respondError(w, http.StatusBadRequest, "INVALID_TENANT", "Invalid tenant")
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(r.Context(), "open tenant session", "tenant", name, "error", err)
+				respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "An unexpected error occurred")
+				return
+			}
+			defer release()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// resolveTenant returns the tenant named by the header, else by the subdomain of baseDomain
+func resolveTenant(r *http.Request, header, baseDomain string) string {
+	var named string
+	if header != "" {
+		named = r.Header.Get(header)
+	}
+	return TenantName(named, r.Host, baseDomain)
+}
+
+// TenantName picks the tenant from the tenant header's value, else from the subdomain of
+// baseDomain in host; "" means the default schema
+// The gRPC interceptor uses it too, so both transports resolve a request the same way
+func TenantName(header, host, baseDomain string) string {
+	if name := strings.TrimSpace(header); name != "" {
+		return strings.ToLower(name)
+	}
+	if baseDomain == "" {
+		return ""
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	sub, ok := strings.CutSuffix(host, "."+strings.ToLower(baseDomain))
+	if !ok {
+		return ""
+	}
+	return sub
+}
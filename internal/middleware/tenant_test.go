@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yourorg/todo-app/internal/tenant"
+)
+
+type tenantKey struct{}
+
+// fakeTenantSessions records opened tenants and tags the context with the tenant name
+type fakeTenantSessions struct {
+	opened   []string
+	released int
+	err      error
+}
+
+func (f *fakeTenantSessions) Open(ctx context.Context, name string) (context.Context, func(), error) {
+	if !tenant.ValidName(name) {
+		return nil, nil, fmt.Errorf("open tenant %q: %w", name, tenant.ErrInvalidName)
+	}
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	f.opened = append(f.opened, name)
+	return context.WithValue(ctx, tenantKey{}, name), func() { f.released++ }, nil
+}
+
+// TestTenant tests tenant resolution from header and subdomain
+func TestTenant(t *testing.T) {
+	testCases := []struct {
+		name       string
+		host       string
+		header     string
+		openErr    error
+		wantCode   int
+		wantTenant string
+	}{
+		{
+			name:       "Header selects tenant",
+			host:       "api.example.com",
+			header:     "acme",
+			wantCode:   http.StatusOK,
+			wantTenant: "acme",
+		},
+		{
+			name:       "Header is case-insensitive",
+			host:       "api.example.com",
+			header:     "ACME",
+			wantCode:   http.StatusOK,
+			wantTenant: "acme",
+		},
+		{
+			name:       "Subdomain selects tenant",
+			host:       "globex.example.com:8080",
+			wantCode:   http.StatusOK,
+			wantTenant: "globex",
+		},
+		{
+			name:       "Header wins over subdomain",
+			host:       "globex.example.com",
+			header:     "acme",
+			wantCode:   http.StatusOK,
+			wantTenant: "acme",
+		},
+		{
+			name:     "No tenant uses default schema",
+			host:     "localhost:8080",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Invalid tenant name",
+			host:     "api.example.com",
+			header:   "acme; DROP SCHEMA public",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Nested subdomain is invalid",
+			host:     "a.b.example.com",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Session failure",
+			host:     "api.example.com",
+			header:   "acme",
+			openErr:  errors.New("connection refused"),
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions := &fakeTenantSessions{err: tc.openErr}
+			var gotTenant string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant, _ = r.Context().Value(tenantKey{}).(string)
+			})
+			handler := Tenant("X-Tenant-ID", "example.com", sessions)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil)
+			req.Host = tc.host
+			if tc.header != "" {
+				req.Header.Set("X-Tenant-ID", tc.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("Expected status %d, got %d. Body: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+			if gotTenant != tc.wantTenant {
+				t.Errorf("Expected tenant %q, got %q", tc.wantTenant, gotTenant)
+			}
+			if sessions.released != len(sessions.opened) {
+				t.Errorf("Expected every opened session to be released, opened %d released %d", len(sessions.opened), sessions.released)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestTimeout tests which requests get a deadline and how long it is
+func TestTimeout(t *testing.T) {
+	testCases := []struct {
+		name         string
+		timeout      time.Duration
+		path         string
+		wantDeadline bool
+	}{
+		{name: "API request", timeout: time.Minute, path: "/api/v1/todos", wantDeadline: true},
+		{name: "Event stream", timeout: time.Minute, path: "/api/v1/todos/events", wantDeadline: false},
+		{name: "Disabled", timeout: 0, path: "/api/v1/todos", wantDeadline: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+			handler := Timeout(tc.timeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, hasDeadline = r.Context().Deadline()
+			}))
+
+			start := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if hasDeadline != tc.wantDeadline {
+				t.Fatalf("Expected deadline %v, got %v", tc.wantDeadline, hasDeadline)
+			}
+			if hasDeadline && (deadline.Before(start.Add(tc.timeout)) || deadline.After(time.Now().Add(tc.timeout))) {
+				t.Errorf("Expected a deadline %s from the request, got %s", tc.timeout, deadline.Sub(start))
+			}
+		})
+	}
+}
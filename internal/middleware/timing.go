@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ProcessingTimeHeader reports how long the server spent on a request, in milliseconds
+const ProcessingTimeHeader = "X-Processing-Time-Ms"
+
+// ProcessingTime middleware stamps each response with the time from handler entry until
+// the response header is written, so clients can tell server time apart from network time
+func ProcessingTime(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &timingWriter{ResponseWriter: w, start: timeNow()}
+		next.ServeHTTP(tw, r)
+
+		// Handlers that write nothing still get an implicit 200 after returning
+		if !tw.wroteHeader {
+			tw.stamp()
+		}
+	})
+}
+
+// timingWriter sets the processing time header just before the header is sent
+type timingWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	wroteHeader bool
+}
+
+func (tw *timingWriter) stamp() {
+	tw.wroteHeader = true
+	elapsed := float64(timeNow().Sub(tw.start)) / float64(time.Millisecond)
+	tw.Header().Set(ProcessingTimeHeader, strconv.FormatFloat(elapsed, 'f', 3, 64))
+}
+
+func (tw *timingWriter) WriteHeader(code int) {
+	if !tw.wroteHeader {
+		tw.stamp()
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timingWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush streams
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
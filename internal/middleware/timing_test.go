@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestProcessingTime tests that every response carries its processing time
+func TestProcessingTime(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		delay   time.Duration
+		wantMs  string
+	}{
+		{
+			name: "Explicit status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+			delay:  1500 * time.Microsecond,
+			wantMs: "1.500",
+		},
+		{
+			name: "Implicit status on write",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			},
+			delay:  42 * time.Millisecond,
+			wantMs: "42.000",
+		},
+		{
+			name:    "Handler writes nothing",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			delay:   0,
+			wantMs:  "0.000",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Each read of the fake clock advances it by delay, so entry-to-write is exactly delay
+			now := time.Unix(0, 0)
+			prev := timeNow
+			timeNow = func() time.Time {
+				t := now
+				now = now.Add(tc.delay)
+				return t
+			}
+			defer func() { timeNow = prev }()
+
+			rr := httptest.NewRecorder()
+			ProcessingTime(tc.handler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/todos", nil))
+
+			got := rr.Header().Get(ProcessingTimeHeader)
+			if got != tc.wantMs {
+				t.Errorf("Expected %s %q, got %q", ProcessingTimeHeader, tc.wantMs, got)
+			}
+			if _, err := strconv.ParseFloat(got, 64); err != nil {
+				t.Errorf("Expected %s to parse as a number, got %q: %v", ProcessingTimeHeader, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TestTracing tests span naming by route pattern, attributes and context propagation
+func TestTracing(t *testing.T) {
+	testCases := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantName    string
+		wantAttrs   map[attribute.Key]attribute.Value
+		wantParent  string
+		wantError   bool
+	}{
+		{
+			name:     "Named after the route pattern",
+			path:     "/api/v1/todos/123",
+			wantName: "GET /api/v1/todos/{id}",
+			wantAttrs: map[attribute.Key]attribute.Value{
+				"http.request.method":       attribute.StringValue("GET"),
+				"http.route":                attribute.StringValue("/api/v1/todos/{id}"),
+				"http.response.status_code": attribute.IntValue(http.StatusOK),
+			},
+		},
+		{
+			name:        "Continues incoming trace context",
+			path:        "/api/v1/todos/123",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantName:    "GET /api/v1/todos/{id}",
+			wantAttrs: map[attribute.Key]attribute.Value{
+				"http.request.method":       attribute.StringValue("GET"),
+				"http.route":                attribute.StringValue("/api/v1/todos/{id}"),
+				"http.response.status_code": attribute.IntValue(http.StatusOK),
+			},
+			wantParent: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:     "Server errors mark the span",
+			path:     "/api/v1/fail",
+			wantName: "GET /api/v1/fail",
+			wantAttrs: map[attribute.Key]attribute.Value{
+				"http.request.method":       attribute.StringValue("GET"),
+				"http.route":                attribute.StringValue("/api/v1/fail"),
+				"http.response.status_code": attribute.IntValue(http.StatusInternalServerError),
+			},
+			wantError: true,
+		},
+		{
+			name:     "Unmatched paths fall back to the method",
+			path:     "/nope",
+			wantName: "GET",
+			wantAttrs: map[attribute.Key]attribute.Value{
+				"http.request.method":       attribute.StringValue("GET"),
+				"http.response.status_code": attribute.IntValue(http.StatusNotFound),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			otel.SetTextMapPropagator(propagation.TraceContext{})
+			defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /api/v1/todos/{id}", func(w http.ResponseWriter, r *http.Request) {})
+			mux.HandleFunc("GET /api/v1/fail", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			Tracing(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+
+			if span.Name() != tc.wantName {
+				t.Errorf("Expected span name %q, got %q", tc.wantName, span.Name())
+			}
+			gotAttrs := map[attribute.Key]attribute.Value{}
+			for _, kv := range span.Attributes() {
+				gotAttrs[kv.Key] = kv.Value
+			}
+			if diff := cmp.Diff(tc.wantAttrs, gotAttrs, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+				t.Errorf("Attributes mismatch (-want +got):\n%s", diff)
+			}
+			if tc.wantParent != "" && span.SpanContext().TraceID().String() != tc.wantParent {
+				t.Errorf("Expected trace ID %s, got %s", tc.wantParent, span.SpanContext().TraceID())
+			}
+			if gotError := span.Status().Code == codes.Error; gotError != tc.wantError {
+				t.Errorf("Expected error status=%v, got %v", tc.wantError, span.Status())
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey records the todo created for a client's Idempotency-Key
+// The key is the primary key, so concurrent requests with the same key can't both insert
+type IdempotencyKey struct {
+	Key       string    `gorm:"type:varchar(255);primaryKey"`
+	TodoID    uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt time.Time `gorm:"not null;index"` // Keys expire a fixed time after this
+}
+
+// TableName specifies the table name for GORM
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
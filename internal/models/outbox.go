@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OutboxEvent is a todo change waiting to be delivered to the webhook
+// It is written in the same transaction as the change, so a crash can't lose it
+type OutboxEvent struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EventType     string     `gorm:"type:varchar(20);not null"`
+	Tenant        string     `gorm:"type:varchar(64);not null;default:''"` // Empty for the default schema
+	Payload       string     `gorm:"type:text;not null"`                   // JSON-encoded TodoEvent
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"type:text;not null;default:''"`
+	NextAttemptAt time.Time  `gorm:"not null;index"`
+	SentAt        *time.Time `gorm:"index"` // Nil while pending
+	CreatedAt     time.Time  `gorm:"not null;autoCreateTime"`
+}
+
+// TableName specifies the table name for GORM
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// BeforeCreate hook to ensure ID is set
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
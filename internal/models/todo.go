@@ -10,22 +10,46 @@ import (
 // Todo represents a task item in the database
 // This is an INTERNAL model - services return protobuf types
 type Todo struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Description string    `gorm:"type:varchar(500);not null;check:length(trim(description)) > 0"`
-	Completed   bool      `gorm:"not null;default:false"`
-	CreatedAt   time.Time `gorm:"not null;autoCreateTime"`
-	UpdatedAt   time.Time `gorm:"not null;autoUpdateTime"`
+	ID             uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Description    string         `gorm:"type:varchar(500);not null;check:length(trim(description)) > 0"`
+	Completed      bool           `gorm:"not null;default:false"`
+	Archived       bool           `gorm:"not null;default:false"`
+	DueDate        *time.Time     `gorm:"default:null"`
+	Priority       string         `gorm:"type:varchar(10);not null;default:'MEDIUM';check:priority IN ('LOW','MEDIUM','HIGH')"`
+	CreatedAt      time.Time      `gorm:"not null;autoCreateTime"`
+	UpdatedAt      time.Time      `gorm:"not null;autoUpdateTime"`
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	Version        int64          `gorm:"not null;default:1"`
+	ParentID       *uuid.UUID     `gorm:"type:uuid;index"`                       // Set on subtasks
+	RecurrenceRule string         `gorm:"type:varchar(100);not null;default:''"` // Canonical rule; empty for one-off todos
+	Position       int64          `gorm:"not null;default:0;index"`              // Manual order, ascending; new todos go last
+	UserID         uuid.UUID      `gorm:"type:uuid;index"`                       // Owner; nil when created without authentication
+	Tags           []Tag          `gorm:"many2many:todo_tags;constraint:OnDelete:CASCADE"`
+	Subtasks       []Todo         `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE"` // Only loaded on request
 }
 
+// Priority values stored in the priority column
+const (
+	PriorityLow    = "LOW"
+	PriorityMedium = "MEDIUM"
+	PriorityHigh   = "HIGH"
+)
+
 // TableName specifies the table name for GORM
 func (Todo) TableName() string {
 	return "todos"
 }
 
+// BeforeUpdate hook bumps Version on every update, so writers can detect concurrent changes
+func (t *Todo) BeforeUpdate(tx *gorm.DB) error {
+	tx.Statement.SetColumn("version", gorm.Expr("version + 1"))
+	return nil
+}
+
 // BeforeCreate hook to ensure ID is set
 func (t *Todo) BeforeCreate(tx *gorm.DB) error {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,132 @@
+package tenant
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidName is returned for tenant names that cannot be mapped to a schema
+var ErrInvalidName = errors.New("invalid tenant name")
+
+// namePattern restricts tenant names to safe, unquoted Postgres identifiers
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,55}$`)
+
+// ValidName reports whether name can be used as a tenant
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Schema returns the Postgres schema holding a tenant's tables
+// The prefix keeps tenants clear of public, pg_catalog and information_schema
+func Schema(name string) string {
+	return "tenant_" + name
+}
+
+type dbKey struct{}
+
+type nameKey struct{}
+
+// WithDB returns a context carrying a tenant-scoped database handle
+func WithDB(ctx context.Context, db *gorm.DB) context.Context {
+	return context.WithValue(ctx, dbKey{}, db)
+}
+
+// DB returns the tenant-scoped handle from ctx, or fallback when the request has no tenant
+func DB(ctx context.Context, fallback *gorm.DB) *gorm.DB {
+	if db, ok := ctx.Value(dbKey{}).(*gorm.DB); ok {
+		return db
+	}
+	return fallback
+}
+
+// Name returns the tenant the request is scoped to, or "" for the default schema
+func Name(ctx context.Context) string {
+	name, _ := ctx.Value(nameKey{}).(string)
+	return name
+}
+
+// Manager opens per-request database sessions pinned to a tenant's schema
+// Each tenant's schema is created and migrated the first time it is used
+type Manager struct {
+	db      *gorm.DB
+	migrate func(*gorm.DB) error
+
+	mu       sync.Mutex
+	migrated map[string]bool
+}
+
+// NewManager creates a Manager that runs migrate inside each new tenant schema
+func NewManager(db *gorm.DB, migrate func(*gorm.DB) error) *Manager {
+	return &Manager{
+		db:       db,
+		migrate:  migrate,
+		migrated: make(map[string]bool),
+	}
+}
+
+// Open pins a connection with search_path set to the tenant's schema
+// The returned context carries the session for DB; release must be called when the request ends
+func (m *Manager) Open(ctx context.Context, name string) (context.Context, func(), error) {
+	if !ValidName(name) {
+		return nil, nil, fmt.Errorf("open tenant %q: %w", name, ErrInvalidName)
+	}
+	schema := Schema(name)
+
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return nil, nil, fmt.Errorf("open tenant %q: %w", name, err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open tenant %q: %w", name, err)
+	}
+
+	// search_path is connection state, so reset it before the connection returns to the pool
+	release := func() {
+		if _, err := conn.ExecContext(context.Background(), "RESET search_path"); err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	session := m.db.Session(&gorm.Session{NewDB: true, Context: ctx})
+	session.Statement.ConnPool = conn
+
+	if err := m.ensureSchema(session, name, schema); err != nil {
+		release()
+		return nil, nil, err
+	}
+	if err := session.Exec(fmt.Sprintf(`SET search_path TO "%s"`, schema)).Error; err != nil {
+		release()
+		return nil, nil, fmt.Errorf("set search_path for tenant %q: %w", name, err)
+	}
+
+	return WithDB(context.WithValue(ctx, nameKey{}, name), session), release, nil
+}
+
+// ensureSchema creates and migrates the tenant's schema once per process
+func (m *Manager) ensureSchema(session *gorm.DB, name, schema string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.migrated[name] {
+		return nil
+	}
+	if err := session.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, schema)).Error; err != nil {
+		return fmt.Errorf("create schema for tenant %q: %w", name, err)
+	}
+	if err := session.Exec(fmt.Sprintf(`SET search_path TO "%s"`, schema)).Error; err != nil {
+		return fmt.Errorf("set search_path for tenant %q: %w", name, err)
+	}
+	if err := m.migrate(session); err != nil {
+		return fmt.Errorf("migrate tenant %q: %w", name, err)
+	}
+	m.migrated[name] = true
+	return nil
+}
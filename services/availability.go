@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+)
+
+// availabilityGuard wraps a TodoService and marks connection-level database failures
+// with ErrServiceUnavailable, so callers can tell "retry later" apart from a real bug
+type availabilityGuard struct {
+	next TodoService
+}
+
+func (g availabilityGuard) Create(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Create(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) CreateIfAbsent(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.CreateIfAbsentResponse, error) {
+	resp, err := g.next.CreateIfAbsent(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) Duplicate(ctx context.Context, req *todov1.DuplicateTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Duplicate(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) BatchCreate(ctx context.Context, req *todov1.BatchCreateTodosRequest) (*todov1.BatchCreateTodosResponse, error) {
+	resp, err := g.next.BatchCreate(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) CompleteAll(ctx context.Context, req *todov1.CompleteAllRequest) (*todov1.CompleteAllResponse, error) {
+	resp, err := g.next.CompleteAll(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) Snapshot(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.TodoSnapshot, error) {
+	snapshot, err := g.next.Snapshot(ctx, req)
+	return snapshot, markUnavailable(err)
+}
+
+func (g availabilityGuard) ImportSnapshot(ctx context.Context, req *todov1.TodoSnapshot) (*todov1.Todo, error) {
+	todo, err := g.next.ImportSnapshot(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) Get(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Get(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) ListSubtasks(ctx context.Context, req *todov1.ListSubtasksRequest) (*todov1.ListSubtasksResponse, error) {
+	resp, err := g.next.ListSubtasks(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) Export(ctx context.Context, each func(*todov1.Todo) error) error {
+	return markUnavailable(g.next.Export(ctx, each))
+}
+
+func (g availabilityGuard) Import(ctx context.Context, req *todov1.ImportTodosRequest) (*ImportResult, error) {
+	result, err := g.next.Import(ctx, req)
+	return result, markUnavailable(err)
+}
+
+func (g availabilityGuard) List(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	resp, err := g.next.List(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) Search(ctx context.Context, req *todov1.SearchTodosRequest) (*todov1.SearchTodosResponse, error) {
+	resp, err := g.next.Search(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) Stats(ctx context.Context) (*todov1.StatsResponse, error) {
+	stats, err := g.next.Stats(ctx)
+	return stats, markUnavailable(err)
+}
+
+func (g availabilityGuard) ListGeneration(ctx context.Context) (*todov1.ListGenerationResponse, error) {
+	resp, err := g.next.ListGeneration(ctx)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) Update(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.UpdateTodoResponse, error) {
+	resp, err := g.next.Update(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) BatchUpdate(ctx context.Context, req *todov1.BatchUpdateTodosRequest) (*BatchUpdateResult, error) {
+	result, err := g.next.BatchUpdate(ctx, req)
+	return result, markUnavailable(err)
+}
+
+func (g availabilityGuard) Delete(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
+	resp, err := g.next.Delete(ctx, req)
+	return resp, markUnavailable(err)
+}
+
+func (g availabilityGuard) DeleteCompleted(ctx context.Context) (int64, error) {
+	deleted, err := g.next.DeleteCompleted(ctx)
+	return deleted, markUnavailable(err)
+}
+
+func (g availabilityGuard) Restore(ctx context.Context, req *todov1.RestoreTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Restore(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) Undo(ctx context.Context) (*todov1.Todo, error) {
+	todo, err := g.next.Undo(ctx)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) Move(ctx context.Context, req *todov1.MoveTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Move(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) Archive(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Archive(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+func (g availabilityGuard) Unarchive(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error) {
+	todo, err := g.next.Unarchive(ctx, req)
+	return todo, markUnavailable(err)
+}
+
+// Subscribe never touches the database, so there is nothing to mark
+func (g availabilityGuard) Subscribe(ctx context.Context) (<-chan *todov1.TodoEvent, func()) {
+	return g.next.Subscribe(ctx)
+}
+
+func (g availabilityGuard) CloseSubscriptions() int {
+	return g.next.CloseSubscriptions()
+}
+
+func (g availabilityGuard) Ping(ctx context.Context) error {
+	return markUnavailable(g.next.Ping(ctx))
+}
+
+// Capabilities is derived from configuration alone, so there is nothing to mark
+func (g availabilityGuard) Capabilities(ctx context.Context) *todov1.CapabilitiesResponse {
+	return g.next.Capabilities(ctx)
+}
+
+// markUnavailable wraps connection-level errors with ErrServiceUnavailable, keeping the cause
+func markUnavailable(err error) error {
+	if err == nil || !isConnectionError(err) || errors.Is(err, ErrServiceUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+}
+
+// isConnectionError reports whether err means the database could not be reached,
+// as opposed to a query the database rejected
+func isConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &connectErr) || errors.As(err, &netErr) {
+		return true
+	}
+
+	// Class 08 is connection exceptions; 57P01-57P03 are server shutdown and startup
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	return false
+}
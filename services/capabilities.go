@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+)
+
+// Optional features reported by Capabilities
+const (
+	FeatureAgeBuckets   = "age_buckets"
+	FeatureArchive      = "archive"
+	FeatureCursorPaging = "cursor_paging"
+	FeatureEvents       = "events"
+	FeatureRecurrence   = "recurrence"
+	FeatureSearch       = "search"
+	FeatureSubtasks     = "subtasks"
+	FeatureSubtaskGuard = "subtask_completion_guard"
+	FeatureUndo         = "undo"
+)
+
+// Capabilities reports the enabled features and the List and Search options this
+// service accepts, after applying its allowlists and limits
+func (s *todoService) Capabilities(ctx context.Context) *todov1.CapabilitiesResponse {
+	features := []string{
+		FeatureAgeBuckets, FeatureArchive, FeatureCursorPaging, FeatureEvents,
+		FeatureRecurrence, FeatureSearch, FeatureSubtasks, FeatureUndo,
+	}
+	if s.subtaskGuard {
+		features = append(features, FeatureSubtaskGuard)
+	}
+	slices.Sort(features)
+
+	resp := &todov1.CapabilitiesResponse{
+		Features:             features,
+		SearchModes:          []string{SearchText, SearchOr, SearchAnd},
+		MinDescriptionLength: int32(s.minDescLen),
+		MaxDescriptionLength: int32(s.maxDescLen),
+		DefaultListFilter:    ListFilterAll,
+	}
+	for _, mode := range []string{SortRandom, SortUrgency} {
+		if allowed(s.sortable, mode) {
+			resp.SortModes = append(resp.SortModes, mode)
+		}
+	}
+	for _, field := range []string{SortByCreatedAt, SortByDescription, SortByPosition, SortByUpdatedAt} {
+		if field == SortByCreatedAt || allowed(s.sortable, field) {
+			resp.SortFields = append(resp.SortFields, field)
+		}
+	}
+	for _, filter := range []string{FilterArchived, FilterCompleted, FilterCreated, FilterDueBefore, FilterDeleted, FilterPriority, FilterTags} {
+		if allowed(s.filterable, filter) {
+			resp.Filters = append(resp.Filters, filter)
+		}
+	}
+	if s.listDone != nil {
+		resp.DefaultListFilter = ListFilterActive
+		if *s.listDone {
+			resp.DefaultListFilter = ListFilterCompleted
+		}
+	}
+	return resp
+}
@@ -1,6 +1,9 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Sentinel errors for the service layer
 // These are wrapped with context using fmt.Errorf("%w") in service methods
@@ -13,4 +16,46 @@ var (
 
 	// ErrEmptyDescription is returned when todo description is empty or whitespace-only
 	ErrEmptyDescription = errors.New("todo description cannot be empty")
-)
\ No newline at end of file
+
+	// ErrDescriptionTooShort is returned when a description is below the configured minimum length
+	ErrDescriptionTooShort = errors.New("todo description is too short")
+
+	// ErrInvalidCharacters is returned when a description contains control characters other than tab and newline
+	ErrInvalidCharacters = errors.New("todo description contains control characters")
+
+	// ErrTodoNotDeleted is returned when restoring a todo that is not in the trash
+	ErrTodoNotDeleted = errors.New("todo is not deleted")
+
+	// ErrNothingToUndo is returned by Undo when no todo was deleted within the undo window
+	ErrNothingToUndo = errors.New("nothing to undo")
+
+	// ErrTodoLocked is returned when a concurrent write holds the todo's lock past the lock timeout; callers may retry
+	ErrTodoLocked = errors.New("todo is locked by a concurrent write")
+
+	// ErrVersionConflict is returned when an update's expected version is not the todo's current version
+	ErrVersionConflict = errors.New("todo version conflict")
+
+	// ErrSubtasksIncomplete is returned when completing a todo that still has open subtasks
+	ErrSubtasksIncomplete = errors.New("todo has incomplete subtasks")
+
+	// ErrPreconditionFailed is returned when an update's expected version no longer matches the todo
+	ErrPreconditionFailed = errors.New("todo was modified since it was read")
+
+	// ErrServiceUnavailable is returned when the database cannot be reached; callers may retry
+	ErrServiceUnavailable = errors.New("service unavailable")
+)
+
+// BatchItemError reports which item of a batch request failed validation
+// It unwraps to the underlying sentinel so callers can still use errors.Is
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
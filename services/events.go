@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Event types published on TodoEvent.Type
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// eventBuffer is how many events a subscriber may fall behind before events are dropped for it
+const eventBuffer = 64
+
+// eventHub is an in-process pub/sub for todo changes
+// Events only reach subscribers of the tenant and user they happened for
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[chan *todov1.TodoEvent]requestScope // subscriber -> scope
+	closed bool                                    // Set by closeAll; later subscribers get a closed channel
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan *todov1.TodoEvent]requestScope)}
+}
+
+// subscribe registers a subscriber for ctx's tenant and user
+// The channel is closed by cancel, or once ctx is done
+func (h *eventHub) subscribe(ctx context.Context) (<-chan *todov1.TodoEvent, func()) {
+	ch := make(chan *todov1.TodoEvent, eventBuffer)
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	h.subs[ch] = requestScopeOf(ctx)
+	h.mu.Unlock()
+
+	// closeAll may have closed the channel already; whoever removes it from subs closes it
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+	return ch, cancel
+}
+
+// publish fans an event out to ctx's tenant and user without blocking the writer
+// A subscriber whose buffer is full misses the event rather than stalling the request
+func (h *eventHub) publish(ctx context.Context, eventType string, todo *todov1.Todo) {
+	event := &todov1.TodoEvent{Type: eventType, Todo: todo, OccurredAt: timestamppb.New(time.Now())}
+	scope := requestScopeOf(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, sub := range h.subs {
+		if sub != scope {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// closeAll disconnects every subscriber and turns away new ones
+// Returns how many subscribers were disconnected
+func (h *eventHub) closeAll() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	n := len(h.subs)
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+	return n
+}
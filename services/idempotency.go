@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errIdempotencyKeyTaken rolls back a Create whose key a concurrent request claimed first
+var errIdempotencyKeyTaken = errors.New("idempotency key taken")
+
+// replayIdempotencyKey returns the todo created under key, or nil when the key is unknown or expired
+// The todo is returned as it is now, even if it has since been deleted
+func (s *todoService) replayIdempotencyKey(ctx context.Context, key string) (*todov1.Todo, error) {
+	var record models.IdempotencyKey
+	err := s.conn(ctx).Where("key = ? AND created_at > ?", key, s.now().Add(-IdempotencyKeyTTL)).First(&record).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query idempotency key: %w", err)
+	}
+
+	var todo models.Todo
+	if err := s.conn(ctx).Unscoped().Preload("Tags").Where("id = ?", record.TodoID).First(&todo).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Purged since; the key is as good as expired
+			return nil, nil
+		}
+		return nil, fmt.Errorf("query todo %s for idempotency key: %w", record.TodoID, err)
+	}
+	return toProto(&todo), nil
+}
+
+// claimIdempotencyKey records key for todoID inside tx, the transaction creating the todo
+// Expired keys are pruned first so they can be reused; reports false when the key is
+// already held, in which case a concurrent insert blocks until the holder commits
+func (s *todoService) claimIdempotencyKey(tx *gorm.DB, key string, todoID uuid.UUID) (bool, error) {
+	if err := tx.Where("created_at <= ?", s.now().Add(-IdempotencyKeyTTL)).Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return false, fmt.Errorf("prune idempotency keys: %w", err)
+	}
+	result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).
+		Create(&models.IdempotencyKey{Key: key, TodoID: todoID, CreatedAt: s.now()})
+	if result.Error != nil {
+		return false, fmt.Errorf("record idempotency key: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
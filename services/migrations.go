@@ -9,6 +9,9 @@ import (
 // This function is exported so external apps can migrate the schema
 func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
+		&models.Tag{},
 		&models.Todo{},
+		&models.OutboxEvent{},
+		&models.IdempotencyKey{},
 	)
 }
\ No newline at end of file
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/internal/models"
+	"github.com/yourorg/todo-app/internal/tenant"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// Outbox dispatch defaults
+const (
+	DefaultOutboxPollInterval = time.Second
+	DefaultOutboxMaxAttempts  = 10
+	outboxBatch               = 100
+	maxOutboxBackoff          = 5 * time.Minute
+	outboxDispatchLock        = "outbox_dispatch"
+)
+
+// enqueue writes an event to the outbox inside tx, the transaction making the change
+// It does nothing unless the service was built WithOutbox
+// Tenant requests run with search_path on their own schema, so their events are
+// written to the default schema's outbox explicitly, where the dispatcher reads them
+func (s *todoService) enqueue(ctx context.Context, tx *gorm.DB, eventType string, todo *todov1.Todo) error {
+	if !s.outbox {
+		return nil
+	}
+	payload, err := json.Marshal(&todov1.TodoEvent{Type: eventType, Todo: todo, OccurredAt: timestamppb.New(s.now())})
+	if err != nil {
+		return fmt.Errorf("encode outbox event: %w", err)
+	}
+
+	event := &models.OutboxEvent{
+		EventType:     eventType,
+		Tenant:        tenant.Name(ctx),
+		Payload:       string(payload),
+		NextAttemptAt: s.now(),
+	}
+	write := tx
+	if event.Tenant != "" {
+		write = tx.Table("public." + event.TableName())
+	}
+	if err := write.Create(event).Error; err != nil {
+		return fmt.Errorf("write outbox event: %w", err)
+	}
+	return nil
+}
+
+// DeliverFunc delivers one outbox event; an error leaves it pending for a retry
+type DeliverFunc func(ctx context.Context, event *models.OutboxEvent) error
+
+// WebhookDeliverer returns a DeliverFunc that POSTs each event's payload to url
+// X-Event-Id lets the receiver drop duplicates, since delivery is at least once
+func WebhookDeliverer(url string, client *http.Client) DeliverFunc {
+	return func(ctx context.Context, event *models.OutboxEvent) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader([]byte(event.Payload)))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Event-Id", event.ID.String())
+		req.Header.Set("X-Event-Type", event.EventType)
+		if event.Tenant != "" {
+			req.Header.Set("X-Tenant", event.Tenant)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook responded %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// OutboxDispatcher delivers pending outbox events and marks them sent
+// An event is only marked sent after delivery succeeds, so a crash in between
+// delivers it again on restart: receivers see every event at least once
+type OutboxDispatcher struct {
+	db          *gorm.DB
+	deliver     DeliverFunc
+	interval    time.Duration
+	maxAttempts int
+	now         func() time.Time
+}
+
+// NewOutboxDispatcher creates a dispatcher draining db's outbox through deliver
+func NewOutboxDispatcher(db *gorm.DB, deliver DeliverFunc) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		db:          db,
+		deliver:     deliver,
+		interval:    DefaultOutboxPollInterval,
+		maxAttempts: DefaultOutboxMaxAttempts,
+		now:         time.Now,
+	}
+}
+
+// WithMaxAttempts sets how many times an event is tried before it is left undelivered
+// (default DefaultOutboxMaxAttempts); values below 1 keep the default
+func (d *OutboxDispatcher) WithMaxAttempts(n int) *OutboxDispatcher {
+	if n > 0 {
+		d.maxAttempts = n
+	}
+	return d
+}
+
+// WithClock replaces the clock used to schedule retries (default time.Now)
+func (d *OutboxDispatcher) WithClock(now func() time.Time) *OutboxDispatcher {
+	d.now = now
+	return d
+}
+
+// Run dispatches pending events every poll interval until ctx is done
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "dispatch outbox", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending makes one pass over the events due for delivery, oldest first
+// A failed event is retried with exponential backoff; the pass carries on with the rest
+// Only one pass runs at a time across every instance sharing the database: a pass
+// that can't take the dispatch lock returns 0 and leaves the events to the holder
+// Returns how many events were delivered
+func (d *OutboxDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	delivered := 0
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", outboxDispatchLock).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("lock outbox dispatch: %w", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		var events []models.OutboxEvent
+		if err := tx.Where("sent_at IS NULL AND attempts < ? AND next_attempt_at <= ?", d.maxAttempts, d.now()).
+			Order("created_at ASC, id ASC").
+			Limit(outboxBatch).
+			Find(&events).Error; err != nil {
+			return fmt.Errorf("query pending outbox events: %w", err)
+		}
+
+		for i := range events {
+			event := &events[i]
+			updates := map[string]interface{}{"attempts": event.Attempts + 1}
+			if err := d.deliver(ctx, event); err != nil {
+				updates["last_error"] = err.Error()
+				updates["next_attempt_at"] = d.now().Add(outboxBackoff(event.Attempts + 1))
+				slog.WarnContext(ctx, "deliver outbox event", "event_id", event.ID, "attempt", event.Attempts+1, "error", err)
+			} else {
+				updates["sent_at"] = d.now()
+				updates["last_error"] = ""
+				delivered++
+			}
+			if err := tx.Model(event).Updates(updates).Error; err != nil {
+				return fmt.Errorf("record outbox event %s: %w", event.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return delivered, nil
+}
+
+// outboxBackoff is the wait before retry number attempts+1: 1s, 2s, 4s... capped at maxOutboxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 20 {
+		return maxOutboxBackoff
+	}
+	return min(time.Second<<(attempts-1), maxOutboxBackoff)
+}
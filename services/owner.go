@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/yourorg/todo-app/internal/auth"
+	"github.com/yourorg/todo-app/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// requestScope is whose data a request works on: a tenant, and within it a user
+// (uuid.Nil without authentication). Per-process state such as event subscriptions
+// and the undo buffer is kept apart by it
+type requestScope struct {
+	tenant string
+	user   uuid.UUID
+}
+
+// requestScopeOf returns the scope of the request in ctx
+func requestScopeOf(ctx context.Context) requestScope {
+	user, _ := auth.UserID(ctx)
+	return requestScope{tenant: tenant.Name(ctx), user: user}
+}
+
+// ownerScopeClause marks a statement the owner scope was already applied to
+const ownerScopeClause = "todo:owner_scope"
+
+// registerOwnerScope confines every statement on the todos table to the authenticated
+// user's todos: creates are stamped with the user, and queries, updates and deletes only
+// match their rows. Doing it in callbacks means no query can forget the condition
+// Requests without a user (authentication disabled) are left unscoped
+// Registration is per *gorm.DB configuration, shared by its sessions and tenant schemas
+func registerOwnerScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if callbacks.Query().Get("todo:owner") != nil {
+		return nil
+	}
+	if err := callbacks.Create().Before("gorm:create").Register("todo:owner", stampOwner); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("todo:owner", scopeToOwner); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("todo:owner", scopeToOwner); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("todo:owner", scopeToOwner); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("todo:owner", scopeToOwner)
+}
+
+// ownedTable reports whether the statement targets the todos table
+func ownedTable(db *gorm.DB) bool {
+	return db.Statement.Schema != nil && db.Statement.Schema.Table == "todos"
+}
+
+// scopeToOwner adds user_id = <user> to a statement on the todos table
+func scopeToOwner(db *gorm.DB) {
+	user, ok := auth.UserID(db.Statement.Context)
+	if !ok || !ownedTable(db) {
+		return
+	}
+	if _, done := db.Statement.Clauses[ownerScopeClause]; done {
+		return
+	}
+	db.Statement.Clauses[ownerScopeClause] = clause.Clause{}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "user_id"}, Value: user},
+	}})
+}
+
+// stampOwner sets UserID on todos being created, one or many
+func stampOwner(db *gorm.DB) {
+	user, ok := auth.UserID(db.Statement.Context)
+	if !ok || !ownedTable(db) {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("UserID")
+	if field == nil {
+		return
+	}
+
+	stamp := func(v reflect.Value) {
+		if err := field.Set(db.Statement.Context, reflect.Indirect(v), user); err != nil {
+			db.AddError(fmt.Errorf("set todo owner: %w", err))
+		}
+	}
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			stamp(rv.Index(i))
+		}
+	case reflect.Struct:
+		stamp(rv)
+	}
+}
+
+// ownedIdempotencyKey namespaces an idempotency key by the authenticated user, so
+// two users sending the same key don't collide; the pair is hashed to fit the key column
+func ownedIdempotencyKey(ctx context.Context, key string) string {
+	user, ok := auth.UserID(ctx)
+	if !ok || key == "" {
+		return key
+	}
+	sum := sha256.Sum256([]byte(user.String() + "/" + key))
+	return "user:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/internal/models"
+	"gorm.io/gorm"
+)
+
+// PositionGap is the spacing between the positions of new todos, so most moves only rewrite the moved todo
+const PositionGap = 1 << 16
+
+// lockPositions serializes the position changes of the request's owner until tx ends,
+// so concurrent creates and moves don't hand out the same position
+func lockPositions(tx *gorm.DB) error {
+	scope := requestScopeOf(tx.Statement.Context)
+	key := fmt.Sprintf("todo_positions:%s:%s", scope.tenant, scope.user)
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+		return fmt.Errorf("lock positions: %w", err)
+	}
+	return nil
+}
+
+// assignPositions places todos without a position after every existing todo, in order
+func assignPositions(tx *gorm.DB, todos ...*models.Todo) error {
+	if err := lockPositions(tx); err != nil {
+		return err
+	}
+	var last int64
+	if err := tx.Model(&models.Todo{}).Unscoped().Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+		return fmt.Errorf("query last position: %w", err)
+	}
+	for _, todo := range todos {
+		if todo.Position == 0 {
+			last += PositionGap
+			todo.Position = last
+		}
+	}
+	return nil
+}
+
+// Move puts a todo at a new index in position order (sort_by=position), counting from 0
+// over every todo outside the trash; an index past the end moves it to the bottom
+// Only the neighbours at index are read and usually only the moved todo changes; when the
+// neighbours leave no room between them, every todo is respaced PositionGap apart first
+func (s *todoService) Move(ctx context.Context, req *todov1.MoveTodoRequest) (*todov1.Todo, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
+	}
+	if req.Position < 0 {
+		return nil, fmt.Errorf("move todo %s: negative position %d: %w", req.Id, req.Position, ErrInvalidInput)
+	}
+
+	var moved *todov1.Todo
+	changed := false
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		// Moves read then rewrite the order, so they take turns
+		if err := lockPositions(tx); err != nil {
+			return err
+		}
+
+		var todo models.Todo
+		if err := tx.Where("id = ?", id).First(&todo).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrTodoNotFound
+			}
+			return err
+		}
+		prev, next, err := neighbours(tx, id, int(req.Position))
+		if err != nil {
+			return fmt.Errorf("query positions: %w", err)
+		}
+
+		position, ok := positionAt(prev, next, todo.Position)
+		if !ok {
+			index := int(req.Position)
+			if err := respace(tx, id, index); err != nil {
+				return err
+			}
+			position = int64(index+1) * PositionGap
+		}
+		if position != todo.Position {
+			if err := tx.Model(&todo).Update("position", position).Error; err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		var reloaded models.Todo
+		if err := tx.Preload("Tags").Where("id = ?", id).First(&reloaded).Error; err != nil {
+			return err
+		}
+		moved = toProto(&reloaded)
+		if !changed {
+			return nil
+		}
+		return s.enqueue(ctx, tx, EventUpdated, moved)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("move todo %s: %w", req.Id, err)
+	}
+	if changed {
+		s.events.publish(ctx, EventUpdated, moved)
+	}
+	return moved, nil
+}
+
+// neighbours returns the todos that would sit just above and below a todo placed at index,
+// ignoring the todo itself; either is nil at the top or bottom of the list
+func neighbours(tx *gorm.DB, id uuid.UUID, index int) (prev, next *models.Todo, err error) {
+	others := tx.Model(&models.Todo{}).Select("id", "position").Where("id <> ?", id)
+	var pair []models.Todo
+	if index == 0 {
+		err = others.Order("position, id").Limit(1).Find(&pair).Error
+	} else {
+		err = others.Order("position, id").Offset(index - 1).Limit(2).Find(&pair).Error
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case index == 0 && len(pair) == 1:
+		return nil, &pair[0], nil
+	case len(pair) == 2:
+		return &pair[0], &pair[1], nil
+	case len(pair) == 1:
+		return &pair[0], nil, nil
+	case index == 0:
+		return nil, nil, nil
+	}
+	// index is past the end: the todo goes after the last one
+	var last models.Todo
+	if err := tx.Select("id", "position").Where("id <> ?", id).Order("position DESC, id DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, nil, err
+	}
+	if last.ID == uuid.Nil {
+		return nil, nil, nil
+	}
+	return &last, nil, nil
+}
+
+// positionAt picks a position for a todo placed between prev and next, either of which
+// is nil at the ends of the list; current is kept when it already lies there. Reports
+// false when the neighbours leave no room between them
+func positionAt(prev, next *models.Todo, current int64) (int64, bool) {
+	switch {
+	case prev == nil && next == nil:
+		return current, true
+	case prev == nil:
+		// Top of the list
+		if current < next.Position {
+			return current, true
+		}
+		return next.Position - PositionGap, true
+	case next == nil:
+		// Bottom of the list
+		if current > prev.Position {
+			return current, true
+		}
+		return prev.Position + PositionGap, true
+	}
+
+	if current > prev.Position && current < next.Position {
+		return current, true
+	}
+	if next.Position-prev.Position < 2 {
+		return 0, false
+	}
+	return prev.Position + (next.Position-prev.Position)/2, true
+}
+
+// respace rewrites the positions of every todo but id PositionGap apart, in their current
+// order, leaving the slot for index free: todos before it take slots 1..index, the rest
+// move down one
+func respace(tx *gorm.DB, id uuid.UUID, index int) error {
+	var others []models.Todo
+	if err := tx.Select("id", "position").Where("id <> ?", id).Order("position, id").Find(&others).Error; err != nil {
+		return fmt.Errorf("query positions: %w", err)
+	}
+	for i := range others {
+		slot := int64(i + 1)
+		if i >= index {
+			slot++
+		}
+		if others[i].Position == slot*PositionGap {
+			continue
+		}
+		if err := tx.Model(&models.Todo{ID: others[i].ID}).Update("position", slot*PositionGap).Error; err != nil {
+			return fmt.Errorf("respace todo %s: %w", others[i].ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies accepted in a recurrence rule's FREQ part
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// maxRecurrenceInterval caps INTERVAL so a typo can't schedule a todo centuries ahead
+const maxRecurrenceInterval = 365
+
+// recurrence is a parsed recurrence rule: every interval days, weeks or months
+type recurrence struct {
+	freq     string
+	interval int
+}
+
+// parseRecurrence parses the supported RRULE subset, e.g. "FREQ=WEEKLY" or "FREQ=DAILY;INTERVAL=2"
+// Parts are case-insensitive and may come in any order; FREQ is required
+func parseRecurrence(rule string) (recurrence, error) {
+	r := recurrence{interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:"), ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || seen[key] {
+			return recurrence{}, fmt.Errorf("recurrence rule %q: malformed part %q: %w", rule, part, ErrInvalidInput)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case FreqDaily, FreqWeekly, FreqMonthly:
+				r.freq = value
+			default:
+				return recurrence{}, fmt.Errorf("recurrence rule %q: unsupported FREQ %q: %w", rule, value, ErrInvalidInput)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxRecurrenceInterval {
+				return recurrence{}, fmt.Errorf("recurrence rule %q: INTERVAL must be 1-%d: %w", rule, maxRecurrenceInterval, ErrInvalidInput)
+			}
+			r.interval = n
+		default:
+			return recurrence{}, fmt.Errorf("recurrence rule %q: unsupported part %q: %w", rule, key, ErrInvalidInput)
+		}
+	}
+	if r.freq == "" {
+		return recurrence{}, fmt.Errorf("recurrence rule %q: missing FREQ: %w", rule, ErrInvalidInput)
+	}
+	return r, nil
+}
+
+// String returns the rule in canonical form, omitting the default INTERVAL=1
+func (r recurrence) String() string {
+	if r.interval == 1 {
+		return "FREQ=" + r.freq
+	}
+	return fmt.Sprintf("FREQ=%s;INTERVAL=%d", r.freq, r.interval)
+}
+
+// next returns the occurrence after t
+// Monthly rules follow time.AddDate, so Jan 31 + 1 month lands on Mar 3 (or Mar 2 in leap years)
+func (r recurrence) next(t time.Time) time.Time {
+	switch r.freq {
+	case FreqDaily:
+		return t.AddDate(0, 0, r.interval)
+	case FreqWeekly:
+		return t.AddDate(0, 0, 7*r.interval)
+	}
+	return t.AddDate(0, r.interval, 0)
+}
@@ -2,72 +2,639 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	todov1 "github.com/yourorg/todo-app/api/gen/v1"
+	"github.com/yourorg/todo-app/internal/auth"
 	"github.com/yourorg/todo-app/internal/models"
+	"github.com/yourorg/todo-app/internal/tenant"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sort modes accepted by List
+const (
+	SortDefault = ""
+	SortRandom  = "random"
+	SortUrgency = "urgency"
+)
+
+// urgencyOrder ranks todos by urgency = priority weight + due weight, highest first:
+//   - priority weight: LOW 1, MEDIUM 2, HIGH 3
+//   - due weight: 3 / (1 + days until due), capped at 3 once due or overdue; 0 without a due date
+//
+// A todo due now weighs as much as a HIGH priority, one due tomorrow half that, and the
+// pull of a due date fades as it moves out. The bind variable is the current Unix time.
+const urgencyOrder = `(CASE priority WHEN 'HIGH' THEN 3 WHEN 'MEDIUM' THEN 2 ELSE 1 END) +
+	(CASE WHEN due_date IS NULL THEN 0 ELSE 3.0 / (1 + GREATEST((EXTRACT(EPOCH FROM due_date) - ?) / 86400.0, 0)) END) DESC,
+	created_at DESC, id DESC`
+
+// Sort fields accepted by List's sort_by; the map keeps user input out of the ORDER BY clause
+const (
+	SortByCreatedAt   = "created_at"
+	SortByUpdatedAt   = "updated_at"
+	SortByDescription = "description"
+	SortByPosition    = "position" // Manual order; ascending unless order=desc
+)
+
+var sortColumns = map[string]string{
+	SortByCreatedAt:   "created_at",
+	SortByUpdatedAt:   "updated_at",
+	SortByDescription: "description",
+	SortByPosition:    "position",
+}
+
+// Sort directions accepted by List's order
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Search modes accepted by Search
+const (
+	SearchText = "text" // Description only (default)
+	SearchOr   = "or"   // Description match OR tagged with the query
+	SearchAnd  = "and"  // Description match AND tagged with the query
+)
+
+// MaxDescriptionLength is the description length limit in characters
+const MaxDescriptionLength = 500
+
+// Description limit modes: whether a description of exactly MaxDescriptionLength chars is allowed
+const (
+	LimitInclusive = "inclusive" // Up to and including the limit (default)
+	LimitExclusive = "exclusive" // Strictly below the limit
+)
+
+// MaxBatchSize caps the number of todos accepted by BatchCreate
+const MaxBatchSize = 100
+
+// Page sizes of List and Search: the default when none is given, and the cap on larger ones
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// IdempotencyKeyTTL is how long Create remembers an idempotency key
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength matches the idempotency_keys.key column
+const maxIdempotencyKeyLength = 255
+
+// MaxImportSize caps the number of todos accepted by Import
+const MaxImportSize = 10000
+
+// DefaultInsertBatchSize is how many rows bulk inserts write per INSERT statement
+const DefaultInsertBatchSize = 500
+
+// DefaultLockTimeout bounds how long Update waits for a row locked by a concurrent write
+const DefaultLockTimeout = time.Second
+
+// lockNotAvailable is the Postgres SQLSTATE raised when lock_timeout expires
+const lockNotAvailable = "55P03"
+
+// SnapshotVersion is the current TodoSnapshot format version
+const SnapshotVersion = 1
+
+// Age buckets set on Todo.AgeBucket, by time since created_at
+const (
+	AgeBucketNew    = "new"    // Under a day old
+	AgeBucketRecent = "recent" // Under a week old
+	AgeBucketAging  = "aging"  // Up to 30 days old
+	AgeBucketStale  = "stale"  // Over 30 days old
+)
+
+// Filters accepted by List
+const (
+	FilterCompleted = "completed"
+	FilterPriority  = "priority"
+	FilterTags      = "tags"
+	FilterDeleted   = "include_deleted"
+	FilterArchived  = "archived"
+	FilterDueBefore = "due_before"
+	FilterCreated   = "created" // created_after and created_before
+)
+
+// Default completed filters applied by List when the client doesn't filter on completed
+const (
+	ListFilterAll       = "all"       // Completed and pending (default)
+	ListFilterActive    = "active"    // Pending only
+	ListFilterCompleted = "completed" // Completed only
 )
 
 // TodoService defines the interface for todo operations
 // All methods use protobuf structs (NO primitives)
 type TodoService interface {
 	Create(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.Todo, error)
+	CreateIfAbsent(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.CreateIfAbsentResponse, error)
+	Duplicate(ctx context.Context, req *todov1.DuplicateTodoRequest) (*todov1.Todo, error)
+	BatchCreate(ctx context.Context, req *todov1.BatchCreateTodosRequest) (*todov1.BatchCreateTodosResponse, error)
+	CompleteAll(ctx context.Context, req *todov1.CompleteAllRequest) (*todov1.CompleteAllResponse, error)
+	Snapshot(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.TodoSnapshot, error)
+	ImportSnapshot(ctx context.Context, req *todov1.TodoSnapshot) (*todov1.Todo, error)
 	Get(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.Todo, error)
+	ListSubtasks(ctx context.Context, req *todov1.ListSubtasksRequest) (*todov1.ListSubtasksResponse, error)
+	Export(ctx context.Context, each func(*todov1.Todo) error) error
+	Import(ctx context.Context, req *todov1.ImportTodosRequest) (*ImportResult, error)
 	List(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error)
-	Update(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.Todo, error)
+	Search(ctx context.Context, req *todov1.SearchTodosRequest) (*todov1.SearchTodosResponse, error)
+	Stats(ctx context.Context) (*todov1.StatsResponse, error)
+	ListGeneration(ctx context.Context) (*todov1.ListGenerationResponse, error)
+	Update(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.UpdateTodoResponse, error)
+	BatchUpdate(ctx context.Context, req *todov1.BatchUpdateTodosRequest) (*BatchUpdateResult, error)
 	Delete(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error)
+	DeleteCompleted(ctx context.Context) (int64, error)
+	Restore(ctx context.Context, req *todov1.RestoreTodoRequest) (*todov1.Todo, error)
+	Undo(ctx context.Context) (*todov1.Todo, error)
+	Move(ctx context.Context, req *todov1.MoveTodoRequest) (*todov1.Todo, error)
+	Archive(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error)
+	Unarchive(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error)
+	Subscribe(ctx context.Context) (<-chan *todov1.TodoEvent, func())
+	CloseSubscriptions() int
+	Capabilities(ctx context.Context) *todov1.CapabilitiesResponse
+	Ping(ctx context.Context) error
+}
+
+// BatchUpdateResult reports how many todos a batch update changed
+// Failures holds one *BatchItemError per ID that could not be updated, indexed into the request IDs;
+// NextOccurrences holds the todos created for recurring todos the batch completed
+type BatchUpdateResult struct {
+	Updated         int64
+	Failures        []*BatchItemError
+	NextOccurrences []*todov1.Todo
+}
+
+// ImportResult reports how many todos an import created
+// Skipped holds one *BatchItemError per invalid todo left out, indexed into the request todos
+type ImportResult struct {
+	Imported int64
+	Skipped  []*BatchItemError
 }
 
 // todoService implements TodoService
 type todoService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	sortable     map[string]bool // nil allows every sort mode
+	filterable   map[string]bool // nil allows every filter
+	minDescLen   int
+	maxDescLen   int           // Longest allowed description, after applying the limit mode
+	listDone     *bool         // Completed filter applied when List has none; nil lists both
+	lockWait     time.Duration // 0 waits indefinitely
+	subtaskGuard bool          // Completing a todo with open subtasks needs Force
+	now          func() time.Time
+	batchSize    int  // Rows per INSERT in bulk inserts
+	outbox       bool // Write every change event to the outbox for webhook delivery
+	events       *eventHub
+	undo         *undoBuffer
 }
 
 // todoServiceBuilder builds a TodoService with optional dependencies
 type todoServiceBuilder struct {
-	db *gorm.DB
+	db           *gorm.DB
+	sortable     []string
+	filterable   []string
+	minDescLen   int
+	limitMode    string
+	listFilter   string
+	lockWait     time.Duration
+	subtaskGuard bool
+	now          func() time.Time
+	batchSize    int
+	outbox       bool
 }
 
 // NewTodoService creates a new TodoService builder
 // Required parameter: db
 func NewTodoService(db *gorm.DB) *todoServiceBuilder {
-	return &todoServiceBuilder{db: db}
+	return &todoServiceBuilder{db: db, minDescLen: 1, lockWait: DefaultLockTimeout, now: time.Now, batchSize: DefaultInsertBatchSize}
+}
+
+// WithListAllowlist restricts the sort modes and filters clients may use in List
+// A nil slice leaves that dimension unrestricted; the default order is always allowed
+func (b *todoServiceBuilder) WithListAllowlist(sortable, filterable []string) *todoServiceBuilder {
+	b.sortable = sortable
+	b.filterable = filterable
+	return b
+}
+
+// WithMinDescriptionLength sets the minimum description length in characters (default 1)
+// The length is measured after trimming whitespace
+func (b *todoServiceBuilder) WithMinDescriptionLength(n int) *todoServiceBuilder {
+	b.minDescLen = n
+	return b
+}
+
+// WithDescriptionLimitMode sets whether a description of exactly MaxDescriptionLength
+// chars is allowed (LimitInclusive, the default) or rejected (LimitExclusive)
+// Unknown modes are treated as LimitInclusive
+func (b *todoServiceBuilder) WithDescriptionLimitMode(mode string) *todoServiceBuilder {
+	b.limitMode = mode
+	return b
+}
+
+// WithDefaultListFilter sets the completed filter List applies when the client sends none:
+// ListFilterAll (the default), ListFilterActive or ListFilterCompleted
+// Unknown filters are treated as ListFilterAll
+func (b *todoServiceBuilder) WithDefaultListFilter(filter string) *todoServiceBuilder {
+	b.listFilter = filter
+	return b
+}
+
+// WithLockTimeout sets how long Update waits for a todo locked by a concurrent write
+// before failing with ErrTodoLocked (default DefaultLockTimeout; 0 waits indefinitely)
+func (b *todoServiceBuilder) WithLockTimeout(d time.Duration) *todoServiceBuilder {
+	b.lockWait = d
+	return b
+}
+
+// WithSubtaskCompletionGuard makes Update refuse to complete a todo while any of its
+// subtasks is open, failing with ErrSubtasksIncomplete unless the request sets Force
+func (b *todoServiceBuilder) WithSubtaskCompletionGuard(enabled bool) *todoServiceBuilder {
+	b.subtaskGuard = enabled
+	return b
+}
+
+// WithInsertBatchSize sets how many rows bulk inserts write per INSERT statement
+// (default DefaultInsertBatchSize); values below 1 keep the default
+func (b *todoServiceBuilder) WithInsertBatchSize(n int) *todoServiceBuilder {
+	if n > 0 {
+		b.batchSize = n
+	}
+	return b
+}
+
+// WithOutbox writes an event to the outbox table in the same transaction as every
+// create, update and delete, for an OutboxDispatcher to deliver
+func (b *todoServiceBuilder) WithOutbox(enabled bool) *todoServiceBuilder {
+	b.outbox = enabled
+	return b
+}
+
+// WithClock replaces the clock used for time-relative results such as age buckets (default time.Now)
+func (b *todoServiceBuilder) WithClock(now func() time.Time) *todoServiceBuilder {
+	b.now = now
+	return b
 }
 
 // Build creates the TodoService instance
+// Connection-level database failures surface as ErrServiceUnavailable
 func (b *todoServiceBuilder) Build() TodoService {
-	return &todoService{
-		db: b.db,
+	if err := registerOwnerScope(b.db); err != nil {
+		panic(fmt.Sprintf("register todo owner scope: %v", err))
 	}
+	return availabilityGuard{next: &todoService{
+		db:           b.db,
+		sortable:     toSet(b.sortable),
+		filterable:   toSet(b.filterable),
+		minDescLen:   b.minDescLen,
+		maxDescLen:   maxDescriptionLength(b.limitMode),
+		listDone:     defaultCompleted(b.listFilter),
+		lockWait:     b.lockWait,
+		subtaskGuard: b.subtaskGuard,
+		now:          b.now,
+		batchSize:    b.batchSize,
+		outbox:       b.outbox,
+		events:       newEventHub(),
+		undo:         newUndoBuffer(),
+	}}
+}
+
+// conn returns the database handle for a request, scoped to its tenant's schema when it has one
+func (s *todoService) conn(ctx context.Context) *gorm.DB {
+	return tenant.DB(ctx, s.db).WithContext(ctx)
 }
 
 // Create creates a new todo item
+// With an idempotency key, a replay within IdempotencyKeyTTL returns the todo first created
 func (s *todoService) Create(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.Todo, error) {
 	// Validate input
-	desc := strings.TrimSpace(req.Description)
-	if desc == "" {
-		return nil, fmt.Errorf("create todo: %w", ErrEmptyDescription)
+	todo, err := s.newTodo(req)
+	if err != nil {
+		return nil, fmt.Errorf("create todo: %w", err)
 	}
-	if len(desc) > 500 {
-		return nil, fmt.Errorf("create todo: description too long (max 500 chars): %w", ErrInvalidInput)
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("create todo: idempotency key longer than %d characters: %w", maxIdempotencyKeyLength, ErrInvalidInput)
 	}
+	key := ownedIdempotencyKey(ctx, req.IdempotencyKey)
 
-	// Create model
-	todo := &models.Todo{
-		Description: desc,
-		Completed:   false,
+	// A replayed key returns the todo it created instead of inserting again
+	if key != "" {
+		if replayed, err := s.replayIdempotencyKey(ctx, key); err != nil || replayed != nil {
+			return replayed, err
+		}
 	}
 
 	// Save to database
-	if err := s.db.WithContext(ctx).Create(todo).Error; err != nil {
+	var created *todov1.Todo
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := insertTodo(tx, todo, req.Tags); err != nil {
+			return err
+		}
+		if key != "" {
+			claimed, err := s.claimIdempotencyKey(tx, key, todo.ID)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return errIdempotencyKeyTaken
+			}
+		}
+		created = toProto(todo)
+		return s.enqueue(ctx, tx, EventCreated, created)
+	})
+	if err != nil && key != "" {
+		// A concurrent request with the same key won, either by holding the key when we
+		// tried to claim it or by failing our commit; return the todo it created
+		if replayed, replayErr := s.replayIdempotencyKey(ctx, key); replayErr == nil && replayed != nil {
+			return replayed, nil
+		}
+	}
+	if err != nil {
 		return nil, fmt.Errorf("create todo in database: %w", err)
 	}
 
-	return toProto(todo), nil
+	s.events.publish(ctx, EventCreated, created)
+	return created, nil
+}
+
+// BatchCreate creates all todos in a single transaction, inserting them in batches
+// Every description is validated up front; if any fails, nothing is written and
+// the returned error is a *BatchItemError carrying the failing index
+func (s *todoService) BatchCreate(ctx context.Context, req *todov1.BatchCreateTodosRequest) (*todov1.BatchCreateTodosResponse, error) {
+	if len(req.Descriptions) == 0 {
+		return nil, fmt.Errorf("batch create todos: no descriptions: %w", ErrInvalidInput)
+	}
+	if len(req.Descriptions) > MaxBatchSize {
+		return nil, fmt.Errorf("batch create todos: more than %d descriptions: %w", MaxBatchSize, ErrInvalidInput)
+	}
+
+	// Validate input
+	todos := make([]*models.Todo, len(req.Descriptions))
+	for i, desc := range req.Descriptions {
+		todo, err := s.newTodo(&todov1.CreateTodoRequest{Description: desc})
+		if err != nil {
+			return nil, fmt.Errorf("batch create todos: %w", &BatchItemError{Index: i, Err: err})
+		}
+		todos[i] = todo
+	}
+
+	// Save to database
+	resp := &todov1.BatchCreateTodosResponse{Todos: make([]*todov1.Todo, len(todos))}
+	if err := s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := assignPositions(tx, todos...); err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(&todos, s.batchSize).Error; err != nil {
+			return err
+		}
+		for i, todo := range todos {
+			resp.Todos[i] = toProto(todo)
+			if err := s.enqueue(ctx, tx, EventCreated, resp.Todos[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("batch create todos in database: %w", err)
+	}
+
+	for _, todo := range resp.Todos {
+		s.events.publish(ctx, EventCreated, todo)
+	}
+	return resp, nil
+}
+
+// CompleteAll marks every incomplete todo as completed in one transaction
+// Already-completed todos are untouched, so repeated calls report 0. Completing a
+// recurring todo creates its next occurrence as Update does. With the subtask guard,
+// a parent stays open when one of its recurring subtasks would come back open under it
+func (s *todoService) CompleteAll(ctx context.Context, req *todov1.CompleteAllRequest) (*todov1.CompleteAllResponse, error) {
+	var updated, created []*todov1.Todo
+	err := s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var open []models.Todo
+		if err := tx.Select("id", "parent_id", "recurrence_rule").Where("completed = ?", false).Find(&open).Error; err != nil {
+			return err
+		}
+		skip := make(map[uuid.UUID]bool)
+		if s.subtaskGuard {
+			parents := make(map[uuid.UUID]*uuid.UUID, len(open))
+			for _, todo := range open {
+				parents[todo.ID] = todo.ParentID
+			}
+			for _, todo := range open {
+				if todo.RecurrenceRule == "" {
+					continue
+				}
+				// The open ancestors of a recurring subtask would each be left with an open subtask
+				for parent := todo.ParentID; parent != nil && !skip[*parent]; {
+					grandparent, ok := parents[*parent]
+					if !ok {
+						break
+					}
+					skip[*parent] = true
+					parent = grandparent
+				}
+			}
+		}
+
+		var ids, recurring []uuid.UUID
+		for _, todo := range open {
+			if skip[todo.ID] {
+				continue
+			}
+			ids = append(ids, todo.ID)
+			if todo.RecurrenceRule != "" {
+				recurring = append(recurring, todo.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// Updates refreshes updated_at on the affected rows
+		result := tx.Model(&models.Todo{}).
+			Where("id IN ? AND completed = ?", ids, false).
+			Updates(map[string]interface{}{"completed": true})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		var done []models.Todo
+		if err := tx.Preload("Tags").Where("id IN ? AND completed = ?", ids, true).Order("position, id").Find(&done).Error; err != nil {
+			return err
+		}
+		for i := range done {
+			todo := toProto(&done[i])
+			if err := s.enqueue(ctx, tx, EventUpdated, todo); err != nil {
+				return err
+			}
+			updated = append(updated, todo)
+		}
+		for _, id := range recurring {
+			next, err := s.createNextOccurrence(tx, id)
+			if err != nil {
+				return err
+			}
+			todo := toProto(next)
+			if err := s.enqueue(ctx, tx, EventCreated, todo); err != nil {
+				return err
+			}
+			created = append(created, todo)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("complete all todos: %w", err)
+	}
+
+	for _, todo := range updated {
+		s.events.publish(ctx, EventUpdated, todo)
+	}
+	for _, todo := range created {
+		s.events.publish(ctx, EventCreated, todo)
+	}
+	return &todov1.CompleteAllResponse{Updated: int32(len(updated))}, nil
+}
+
+// CreateIfAbsent creates a todo unless an active todo with the same description exists
+// Concurrent calls for the same description are serialized with a transaction-scoped advisory lock
+func (s *todoService) CreateIfAbsent(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.CreateIfAbsentResponse, error) {
+	// Validate input
+	todo, err := s.newTodo(req)
+	if err != nil {
+		return nil, fmt.Errorf("create todo if absent: %w", err)
+	}
+
+	created := false
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", todo.Description).Error; err != nil {
+			return fmt.Errorf("lock description: %w", err)
+		}
+
+		var existing models.Todo
+		err := tx.Preload("Tags").Where("description = ? AND completed = ?", todo.Description, false).Order("created_at").First(&existing).Error
+		if err == nil {
+			todo = &existing
+			return nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("query active todo: %w", err)
+		}
+
+		if err := insertTodo(tx, todo, req.Tags); err != nil {
+			return err
+		}
+		created = true
+		return s.enqueue(ctx, tx, EventCreated, toProto(todo))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create todo if absent: %w", err)
+	}
+
+	resp := &todov1.CreateIfAbsentResponse{
+		Todo:    toProto(todo),
+		Created: created,
+	}
+	if created {
+		s.events.publish(ctx, EventCreated, resp.Todo)
+	}
+	return resp, nil
+}
+
+// copySuffix is appended to the description of a duplicated todo
+const copySuffix = " (copy)"
+
+// Duplicate creates an incomplete copy of a todo under a fresh ID, its description suffixed " (copy)"
+// A description too long to take the suffix is cut short to make room for it
+// The copy keeps the due date, priority, tags, recurrence rule and parent; subtasks are not copied
+func (s *todoService) Duplicate(ctx context.Context, req *todov1.DuplicateTodoRequest) (*todov1.Todo, error) {
+	source, err := s.Get(ctx, &todov1.GetTodoRequest{Id: req.Id})
+	if err != nil {
+		return nil, fmt.Errorf("duplicate todo: %w", err)
+	}
+
+	createReq := recreateRequest(source)
+	if desc, room := []rune(createReq.Description), s.maxDescLen-utf8.RuneCountInString(copySuffix); len(desc) > room {
+		createReq.Description = strings.TrimRightFunc(string(desc[:room]), unicode.IsSpace)
+	}
+	createReq.Description += copySuffix
+	if source.ParentId != "" {
+		createReq.ParentId = &source.ParentId
+	}
+	todo, err := s.Create(ctx, createReq)
+	if err != nil {
+		return nil, fmt.Errorf("duplicate todo %s: %w", req.Id, err)
+	}
+	return todo, nil
+}
+
+// Snapshot exports a single todo as a self-contained snapshot for re-import elsewhere
+func (s *todoService) Snapshot(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.TodoSnapshot, error) {
+	todo, err := s.Get(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot todo: %w", err)
+	}
+
+	return &todov1.TodoSnapshot{
+		Version: SnapshotVersion,
+		Todo:    todo,
+	}, nil
+}
+
+// ImportSnapshot recreates a snapshotted todo under a new ID
+// Content is validated with the same rules as Create; the original created_at and
+// updated_at are preserved when the snapshot carries them
+func (s *todoService) ImportSnapshot(ctx context.Context, req *todov1.TodoSnapshot) (*todov1.Todo, error) {
+	if req.Version != SnapshotVersion {
+		return nil, fmt.Errorf("import snapshot: unsupported version %d: %w", req.Version, ErrInvalidInput)
+	}
+	if req.Todo == nil {
+		return nil, fmt.Errorf("import snapshot: missing todo: %w", ErrInvalidInput)
+	}
+
+	createReq := recreateRequest(req.Todo)
+	todo, err := s.newTodo(createReq)
+	if err != nil {
+		return nil, fmt.Errorf("import snapshot: %w", err)
+	}
+	todo.Completed = req.Todo.Completed
+	if err := backdate(todo, req.Todo.CreatedAt, req.Todo.UpdatedAt, s.now()); err != nil {
+		return nil, fmt.Errorf("import snapshot: %w", err)
+	}
+
+	var imported *todov1.Todo
+	if err := s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := insertTodo(tx, todo, createReq.Tags); err != nil {
+			return err
+		}
+		imported = toProto(todo)
+		return s.enqueue(ctx, tx, EventCreated, imported)
+	}); err != nil {
+		return nil, fmt.Errorf("import snapshot in database: %w", err)
+	}
+
+	s.events.publish(ctx, EventCreated, imported)
+	return imported, nil
 }
 
 // Get retrieves a single todo by ID
@@ -80,7 +647,7 @@ func (s *todoService) Get(ctx context.Context, req *todov1.GetTodoRequest) (*tod
 
 	// Query database
 	var todo models.Todo
-	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&todo).Error; err != nil {
+	if err := s.conn(ctx).Preload("Tags").Where("id = ?", id).First(&todo).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("get todo %s: %w", req.Id, ErrTodoNotFound)
 		}
@@ -90,15 +657,166 @@ func (s *todoService) Get(ctx context.Context, req *todov1.GetTodoRequest) (*tod
 	return toProto(&todo), nil
 }
 
+// ListSubtasks returns a todo's direct subtasks, oldest first
+func (s *todoService) ListSubtasks(ctx context.Context, req *todov1.ListSubtasksRequest) (*todov1.ListSubtasksResponse, error) {
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
+	}
+
+	var parent models.Todo
+	if err := s.conn(ctx).Preload("Subtasks", func(db *gorm.DB) *gorm.DB {
+		return db.Preload("Tags").Order("created_at ASC, id ASC")
+	}).Where("id = ?", id).First(&parent).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("list subtasks of %s: %w", req.Id, ErrTodoNotFound)
+		}
+		return nil, fmt.Errorf("query subtasks of %s: %w", req.Id, err)
+	}
+
+	resp := &todov1.ListSubtasksResponse{Todos: make([]*todov1.Todo, 0, len(parent.Subtasks))}
+	for i := range parent.Subtasks {
+		resp.Todos = append(resp.Todos, toProto(&parent.Subtasks[i]))
+	}
+	return resp, nil
+}
+
+// exportBatch is how many todos Export reads per query
+const exportBatch = 500
+
+// Export calls each for every todo, oldest first, reading them in batches so the
+// whole table is never held in memory; archived and completed todos are included,
+// trashed ones are not. An error from each stops the export and is returned as is
+func (s *todoService) Export(ctx context.Context, each func(*todov1.Todo) error) error {
+	var last *models.Todo
+	for {
+		query := s.conn(ctx).Preload("Tags").Order("created_at ASC, id ASC").Limit(exportBatch)
+		if last != nil {
+			query = query.Where("created_at > ? OR (created_at = ? AND id > ?)", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		var todos []models.Todo
+		if err := query.Find(&todos).Error; err != nil {
+			return fmt.Errorf("export todos: %w", err)
+		}
+		for i := range todos {
+			if err := each(toProto(&todos[i])); err != nil {
+				return err
+			}
+		}
+		if len(todos) < exportBatch {
+			return nil
+		}
+		last = &todos[len(todos)-1]
+	}
+}
+
+// Import recreates exported todos with new IDs in one transaction, inserting them in
+// batches; content and completed state are kept, IDs, timestamps and parents are not
+// Every todo is validated like Create. An invalid todo fails the whole import with a
+// *BatchItemError, unless SkipInvalid is set, in which case it is reported and left out
+func (s *todoService) Import(ctx context.Context, req *todov1.ImportTodosRequest) (*ImportResult, error) {
+	if len(req.Todos) > MaxImportSize {
+		return nil, fmt.Errorf("import todos: more than %d todos: %w", MaxImportSize, ErrInvalidInput)
+	}
+
+	result := &ImportResult{}
+	todos := make([]*models.Todo, 0, len(req.Todos))
+	tagNames := make([][]string, 0, len(req.Todos))
+	for i, item := range req.Todos {
+		createReq := recreateRequest(item)
+		todo, err := s.newTodo(createReq)
+		if err != nil {
+			if !req.SkipInvalid {
+				return nil, fmt.Errorf("import todos: %w", &BatchItemError{Index: i, Err: err})
+			}
+			result.Skipped = append(result.Skipped, &BatchItemError{Index: i, Err: err})
+			continue
+		}
+		todo.Completed = item.Completed
+		todos = append(todos, todo)
+		tagNames = append(tagNames, createReq.Tags)
+	}
+
+	created := make([]*todov1.Todo, len(todos))
+	err := s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(todos) == 0 {
+			return nil
+		}
+		if err := attachTags(tx, todos, tagNames); err != nil {
+			return err
+		}
+		if err := assignPositions(tx, todos...); err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(&todos, s.batchSize).Error; err != nil {
+			return err
+		}
+		for i, todo := range todos {
+			created[i] = toProto(todo)
+			if err := s.enqueue(ctx, tx, EventCreated, created[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("import todos in database: %w", err)
+	}
+
+	for _, todo := range created {
+		s.events.publish(ctx, EventCreated, todo)
+	}
+	result.Imported = int64(len(created))
+	return result, nil
+}
+
+// recreateRequest builds the create request that recreates an exported todo's content
+func recreateRequest(todo *todov1.Todo) *todov1.CreateTodoRequest {
+	req := &todov1.CreateTodoRequest{
+		Description:    todo.GetDescription(),
+		Priority:       todo.GetPriority(),
+		Tags:           todo.GetTags(),
+		RecurrenceRule: todo.GetRecurrenceRule(),
+	}
+	if todo.GetDueDate() != nil {
+		due := todo.DueDate.AsTime().Format(time.RFC3339Nano)
+		req.DueDate = &due
+	}
+	return req
+}
+
 // List retrieves todos with pagination and optional filtering
 func (s *todoService) List(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	// Cursor paging takes over from limit/offset when requested
+	cursorPaging := req.PageToken != "" || req.PageSize > 0
+	if cursorPaging && req.Sort != SortDefault {
+		return nil, fmt.Errorf("list todos: cursor paging requires the default sort: %w", ErrInvalidInput)
+	}
+	if cursorPaging && req.Offset > 0 {
+		return nil, fmt.Errorf("list todos: page_token cannot be combined with offset: %w", ErrInvalidInput)
+	}
+	sortBy, desc, err := parseSortField(req.SortBy, req.Order)
+	if err != nil {
+		return nil, fmt.Errorf("list todos: %w", err)
+	}
+	customOrder := sortBy != SortByCreatedAt || !desc
+	if customOrder && req.Sort != SortDefault {
+		return nil, fmt.Errorf("list todos: sort_by cannot be combined with sort %q: %w", req.Sort, ErrInvalidInput)
+	}
+	if customOrder && cursorPaging {
+		return nil, fmt.Errorf("list todos: cursor paging requires the default order: %w", ErrInvalidInput)
+	}
+
 	// Set defaults
 	limit := req.Limit
+	if cursorPaging {
+		limit = req.PageSize
+	}
 	if limit <= 0 {
-		limit = 20
+		limit = DefaultListLimit
 	}
-	if limit > 100 {
-		limit = 100
+	if limit > MaxListLimit {
+		limit = MaxListLimit
 	}
 
 	offset := req.Offset
@@ -106,91 +824,647 @@ func (s *todoService) List(ctx context.Context, req *todov1.ListTodosRequest) (*
 		offset = 0
 	}
 
+	// Enforce the deployment's allowlist
+	if req.Sort != SortDefault && !allowed(s.sortable, req.Sort) {
+		return nil, fmt.Errorf("list todos: sort %q not allowed: %w", req.Sort, ErrInvalidInput)
+	}
+	if sortBy != SortByCreatedAt && !allowed(s.sortable, sortBy) {
+		return nil, fmt.Errorf("list todos: sort_by %q not allowed: %w", sortBy, ErrInvalidInput)
+	}
+	if req.Completed != nil && !allowed(s.filterable, FilterCompleted) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterCompleted, ErrInvalidInput)
+	}
+	if req.Priority != nil && !allowed(s.filterable, FilterPriority) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterPriority, ErrInvalidInput)
+	}
+	if len(req.Tags) > 0 && !allowed(s.filterable, FilterTags) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterTags, ErrInvalidInput)
+	}
+	if req.IncludeDeleted && !allowed(s.filterable, FilterDeleted) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterDeleted, ErrInvalidInput)
+	}
+	if req.Archived != nil && !allowed(s.filterable, FilterArchived) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterArchived, ErrInvalidInput)
+	}
+	if req.DueBefore != "" && !allowed(s.filterable, FilterDueBefore) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterDueBefore, ErrInvalidInput)
+	}
+	if (req.CreatedAfter != "" || req.CreatedBefore != "") && !allowed(s.filterable, FilterCreated) {
+		return nil, fmt.Errorf("list todos: filter %q not allowed: %w", FilterCreated, ErrInvalidInput)
+	}
+
+	// Resolve sort order
+	var seed int64
+	// ID breaks created_at ties so cursors address a unique position
+	order := clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: sortColumns[sortBy]}, Desc: desc},
+		{Column: clause.Column{Name: "id"}, Desc: desc},
+	}}
+	switch req.Sort {
+	case SortDefault:
+	case SortRandom:
+		// Order by a hash of ID and seed so the same seed pages through the same shuffle
+		if req.Seed != nil {
+			seed = *req.Seed
+		} else {
+			seed = rand.Int64()
+		}
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL:  "md5(id::text || ?), id",
+			Vars: []interface{}{strconv.FormatInt(seed, 10)},
+		}}
+	case SortUrgency:
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL:  urgencyOrder,
+			Vars: []interface{}{time.Now().Unix()},
+		}}
+	default:
+		return nil, fmt.Errorf("list todos: unknown sort %q: %w", req.Sort, ErrInvalidInput)
+	}
+
 	// Build query
-	query := s.db.WithContext(ctx).Model(&models.Todo{})
+	// Raw drops the default filters, but only admins may see the trash that way
+	base := s.conn(ctx).Model(&models.Todo{})
+	if req.IncludeDeleted || (req.Raw && auth.IsAdmin(ctx)) {
+		base = base.Unscoped()
+	}
+	// Archived todos are hidden unless the client filters on them
+	if req.Archived == nil && !req.Raw {
+		base = base.Where("archived = ?", false)
+	}
+	// The deployment's default completed filter gives way to an explicit one
+	if req.Completed == nil && s.listDone != nil && !req.Raw {
+		base = base.Where("completed = ?", *s.listDone)
+	}
+	query := base.Session(&gorm.Session{})
 
 	// Apply filter if specified
+	filtered := false
 	if req.Completed != nil {
 		query = query.Where("completed = ?", *req.Completed)
+		filtered = true
+	}
+	if req.Archived != nil {
+		query = query.Where("archived = ?", *req.Archived)
+		filtered = true
+	}
+	if req.Priority != nil {
+		priority, err := priorityToModel(*req.Priority)
+		if err != nil {
+			return nil, fmt.Errorf("list todos: %w", err)
+		}
+		query = query.Where("priority = ?", priority)
+		filtered = true
+	}
+	if req.DueBefore != "" {
+		dueBefore, err := parseDueDate(req.DueBefore)
+		if err != nil {
+			return nil, fmt.Errorf("list todos: %w", err)
+		}
+		query = query.Where("due_date IS NOT NULL AND due_date < ?", *dueBefore)
+		filtered = true
+	}
+	if req.CreatedAfter != "" || req.CreatedBefore != "" {
+		after, before, err := parseCreatedRange(req.CreatedAfter, req.CreatedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("list todos: %w", err)
+		}
+		if after != nil {
+			query = query.Where("created_at >= ?", *after)
+		}
+		if before != nil {
+			query = query.Where("created_at <= ?", *before)
+		}
+		filtered = true
+	}
+	if len(req.Tags) > 0 {
+		names, err := normalizeTags(req.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("list todos: %w", err)
+		}
+		query = query.Where("EXISTS (SELECT 1 FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id WHERE todo_tags.todo_id = todos.id AND tags.name IN ?)", names)
+		filtered = true
 	}
 
-	// Count total
+	// Count total, optionally including the trash even though it is not listed
+	countQuery, countBase := query, base
+	if req.CountDeleted {
+		// Sessions keep Unscoped from leaking into the listing query
+		countQuery = query.Session(&gorm.Session{}).Unscoped()
+		countBase = base.Session(&gorm.Session{}).Unscoped()
+	}
 	var total int64
-	if err := query.Count(&total).Error; err != nil {
+	if err := countQuery.Count(&total).Error; err != nil {
 		return nil, fmt.Errorf("count todos: %w", err)
 	}
 
+	// Count without filters so clients can show "N of M"
+	totalUnfiltered := total
+	if filtered {
+		if err := countBase.Count(&totalUnfiltered).Error; err != nil {
+			return nil, fmt.Errorf("count unfiltered todos: %w", err)
+		}
+	}
+
+	// Resume after the cursor position
+	if cursorPaging && req.PageToken != "" {
+		cursor, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, fmt.Errorf("list todos: %w", err)
+		}
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+	}
+
+	// Fetch one extra row to learn whether another page exists when total can't tell:
+	// cursor pages don't start at an offset, and count_deleted counts rows that aren't listed
+	fetch := int(limit)
+	peek := cursorPaging || req.CountDeleted
+	if peek {
+		fetch++
+	}
+
 	// Query todos
 	var todos []models.Todo
-	if err := query.Order("created_at DESC").Limit(int(limit)).Offset(int(offset)).Find(&todos).Error; err != nil {
+	// Tags are preloaded in one batched query regardless of page size
+	if err := query.Preload("Tags").Order(order).Limit(fetch).Offset(int(offset)).Find(&todos).Error; err != nil {
 		return nil, fmt.Errorf("list todos: %w", err)
 	}
 
+	var nextPageToken string
+	var nextOffset int32
+	hasMore := offset+int32(len(todos)) < int32(total)
+	if peek {
+		hasMore = len(todos) > int(limit)
+		if hasMore {
+			todos = todos[:limit]
+		}
+	}
+	switch {
+	case hasMore && cursorPaging:
+		nextPageToken = encodePageToken(&todos[len(todos)-1])
+	case hasMore:
+		nextOffset = offset + int32(len(todos))
+	}
+
 	// Convert to protobuf
 	pbTodos := make([]*todov1.Todo, len(todos))
+	now := s.now()
 	for i, todo := range todos {
 		pbTodos[i] = toProto(&todo)
+		if req.WithAgeBucket {
+			pbTodos[i].AgeBucket = ageBucket(now.Sub(todo.CreatedAt))
+		}
 	}
 
 	return &todov1.ListTodosResponse{
-		Todos:  pbTodos,
-		Total:  int32(total),
-		Limit:  limit,
-		Offset: offset,
+		Todos:           pbTodos,
+		Total:           int32(total),
+		Limit:           limit,
+		Offset:          offset,
+		Seed:            seed,
+		TotalUnfiltered: int32(totalUnfiltered),
+		NextPageToken:   nextPageToken,
+		NextOffset:      nextOffset,
+		HasMore:         hasMore,
 	}, nil
 }
 
-// Update updates a todo item
-func (s *todoService) Update(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.Todo, error) {
-	// Parse UUID
-	id, err := uuid.Parse(req.Id)
-	if err != nil {
-		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
+// Search finds todos whose description contains the query, ignoring case, newest first
+// The or/and modes also match todos tagged with the query
+func (s *todoService) Search(ctx context.Context, req *todov1.SearchTodosRequest) (*todov1.SearchTodosResponse, error) {
+	q := strings.TrimSpace(req.Q)
+	if q == "" {
+		return nil, fmt.Errorf("search todos: empty query: %w", ErrInvalidInput)
 	}
 
-	// Find existing todo
-	var todo models.Todo
-	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&todo).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("update todo %s: %w", req.Id, ErrTodoNotFound)
-		}
-		return nil, fmt.Errorf("query todo %s: %w", req.Id, err)
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
 	}
 
-	// Apply updates
-	updates := make(map[string]interface{})
-
-	if req.Description != nil {
-		desc := strings.TrimSpace(*req.Description)
-		if desc == "" {
-			return nil, fmt.Errorf("update todo: %w", ErrEmptyDescription)
-		}
-		if len(desc) > 500 {
-			return nil, fmt.Errorf("update todo: description too long (max 500 chars): %w", ErrInvalidInput)
-		}
-		updates["description"] = desc
+	textMatch := "lower(description) LIKE lower(@pattern)"
+	tagMatch := "EXISTS (SELECT 1 FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id WHERE todo_tags.todo_id = todos.id AND tags.name = @tag)"
+	var cond string
+	switch req.Mode {
+	case "", SearchText:
+		cond = textMatch
+	case SearchOr:
+		cond = "(" + textMatch + " OR " + tagMatch + ")"
+	case SearchAnd:
+		cond = textMatch + " AND " + tagMatch
+	default:
+		return nil, fmt.Errorf("search todos: unknown mode %q: %w", req.Mode, ErrInvalidInput)
 	}
 
-	if req.Completed != nil {
-		updates["completed"] = *req.Completed
+	var todos []models.Todo
+	if err := s.conn(ctx).Preload("Tags").
+		Where(cond, sql.Named("pattern", "%"+escapeLike(q)+"%"), sql.Named("tag", strings.ToLower(q))).
+		Order("created_at DESC, id DESC").
+		Limit(int(limit)).
+		Find(&todos).Error; err != nil {
+		return nil, fmt.Errorf("search todos: %w", err)
+	}
+
+	pbTodos := make([]*todov1.Todo, len(todos))
+	for i, todo := range todos {
+		pbTodos[i] = toProto(&todo)
+	}
+
+	return &todov1.SearchTodosResponse{Todos: pbTodos}, nil
+}
+
+// Stats returns aggregate counts computed in a single query
+func (s *todoService) Stats(ctx context.Context) (*todov1.StatsResponse, error) {
+	var row struct {
+		Total          int64
+		Completed      int64
+		CreatedLast24h int64
+	}
+	since := time.Now().Add(-24 * time.Hour)
+	if err := s.conn(ctx).Model(&models.Todo{}).
+		Select("COUNT(*) AS total, "+
+			"COUNT(CASE WHEN completed THEN 1 END) AS completed, "+
+			"COUNT(CASE WHEN created_at >= ? THEN 1 END) AS created_last24h", since).
+		Scan(&row).Error; err != nil {
+		return nil, fmt.Errorf("todo stats: %w", err)
+	}
+
+	return &todov1.StatsResponse{
+		Total:           int32(row.Total),
+		Completed:       int32(row.Completed),
+		Pending:         int32(row.Total - row.Completed),
+		CreatedLast_24H: int32(row.CreatedLast24h),
+	}, nil
+}
+
+// ListGeneration fingerprints the todo table, trash included, so that any create,
+// update, delete or restore changes it; it is cheap enough to check before a List
+func (s *todoService) ListGeneration(ctx context.Context) (*todov1.ListGenerationResponse, error) {
+	var row struct {
+		Count      int64
+		LastUpdate *time.Time
+		LastDelete *time.Time
+	}
+	err := s.conn(ctx).Unscoped().Model(&models.Todo{}).
+		Select("COUNT(*) AS count, MAX(updated_at) AS last_update, MAX(deleted_at) AS last_delete").
+		Scan(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("query list generation: %w", err)
+	}
+
+	micros := func(t *time.Time) int64 {
+		if t == nil {
+			return 0
+		}
+		return t.UnixMicro()
+	}
+	return &todov1.ListGenerationResponse{
+		Generation: fmt.Sprintf("%d-%d-%d", row.Count, micros(row.LastUpdate), micros(row.LastDelete)),
+	}, nil
+}
+
+// Update updates a todo item
+// Requests that leave every field at its current value skip the write and report NoOp
+func (s *todoService) Update(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.UpdateTodoResponse, error) {
+	// Parse UUID
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
+	}
+
+	// Validate the whole request before touching the database
+	changes, err := s.newChangeSet(req)
+	if err != nil {
+		return nil, fmt.Errorf("update todo: %w", err)
+	}
+	updates, replaceTags := changes.updates, changes.replaceTags
+
+	// Find existing todo
+	var todo models.Todo
+	if err := s.conn(ctx).Preload("Tags").Where("id = ?", id).First(&todo).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("update todo %s: %w", req.Id, ErrTodoNotFound)
+		}
+		return nil, fmt.Errorf("query todo %s: %w", req.Id, err)
+	}
+	if req.ExpectedVersion != nil && todo.Version != *req.ExpectedVersion {
+		return nil, fmt.Errorf("update todo %s at version %d: %w", req.Id, *req.ExpectedVersion, ErrVersionConflict)
+	}
+	if req.ExpectedUpdatedAt != nil && !todo.UpdatedAt.Equal(req.ExpectedUpdatedAt.AsTime()) {
+		return nil, fmt.Errorf("update todo %s: %w", req.Id, ErrPreconditionFailed)
+	}
+
+	// Skip the write entirely when nothing would change
+	dropUnchanged(&todo, updates)
+	if replaceTags {
+		replaceTags = !slices.Equal(changes.tags, tagNames(todo.Tags))
+	}
+	if len(updates) == 0 && !replaceTags {
+		return &todov1.UpdateTodoResponse{Todo: toProto(&todo), NoOp: true}, nil
+	}
+	completing, _ := updates["completed"].(bool)
+	if completing && s.subtaskGuard && !req.Force {
+		var open int64
+		if err := s.conn(ctx).Model(&models.Todo{}).Where("parent_id = ? AND completed = ?", id, false).Count(&open).Error; err != nil {
+			return nil, fmt.Errorf("count subtasks of %s: %w", req.Id, err)
+		}
+		if open > 0 {
+			return nil, fmt.Errorf("complete todo %s with %d open subtasks: %w", req.Id, open, ErrSubtasksIncomplete)
+		}
+	}
+
+	// Tag-only changes still touch the row so updated_at and version move
+	if len(updates) == 0 {
+		updates["updated_at"] = time.Now()
 	}
 
 	// Update in database
-	if len(updates) > 0 {
-		if err := s.db.WithContext(ctx).Model(&todo).Updates(updates).Error; err != nil {
-			return nil, fmt.Errorf("update todo %s in database: %w", req.Id, err)
+	var updated, next *todov1.Todo
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.setLockTimeout(tx); err != nil {
+			return err
 		}
+		// Re-check the expected state in the write itself, so a concurrent update can't slip in
+		write := tx.Model(&todo)
+		if req.ExpectedVersion != nil {
+			write = write.Where("version = ?", todo.Version)
+		}
+		if req.ExpectedUpdatedAt != nil {
+			write = write.Where("updated_at = ?", todo.UpdatedAt)
+		}
+		if parentID, ok := updates["parent_id"].(*uuid.UUID); ok && parentID != nil {
+			if err := checkParent(tx, id, *parentID); err != nil {
+				return err
+			}
+		}
+		result := write.Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			switch {
+			case req.ExpectedVersion != nil:
+				return ErrVersionConflict
+			case req.ExpectedUpdatedAt != nil:
+				return ErrPreconditionFailed
+			}
+			return ErrTodoNotFound
+		}
+		if replaceTags {
+			tags, err := resolveTags(tx, req.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&todo).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+
+		// Reload into a fresh model so columns cleared to NULL don't keep their old values
+		var reloaded models.Todo
+		if err := tx.Preload("Tags").Where("id = ?", id).First(&reloaded).Error; err != nil {
+			return err
+		}
+		updated = toProto(&reloaded)
+		if err := s.enqueue(ctx, tx, EventUpdated, updated); err != nil {
+			return err
+		}
+
+		if completing && todo.RecurrenceRule != "" {
+			nextTodo, err := s.createNextOccurrence(tx, id)
+			if err != nil {
+				return err
+			}
+			next = toProto(nextTodo)
+			return s.enqueue(ctx, tx, EventCreated, next)
+		}
+		return nil
+	})
+	if isLockTimeout(err) {
+		return nil, fmt.Errorf("update todo %s: %w", req.Id, ErrTodoLocked)
+	}
+	if errors.Is(err, ErrVersionConflict) || errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrTodoNotFound) || errors.Is(err, ErrInvalidInput) {
+		return nil, fmt.Errorf("update todo %s: %w", req.Id, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update todo %s in database: %w", req.Id, err)
 	}
 
-	// Reload to get updated values
-	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&todo).Error; err != nil {
-		return nil, fmt.Errorf("reload todo %s: %w", req.Id, err)
+	s.events.publish(ctx, EventUpdated, updated)
+	resp := &todov1.UpdateTodoResponse{Todo: updated}
+	if next != nil {
+		resp.NextOccurrence = next
+		s.events.publish(ctx, EventCreated, next)
 	}
+	return resp, nil
+}
 
-	return toProto(&todo), nil
+// createNextOccurrence creates the open successor of a just-completed recurring todo
+// It copies the todo as written by the update and advances the due date by the rule,
+// counting from now when the todo has no due date
+func (s *todoService) createNextOccurrence(tx *gorm.DB, id uuid.UUID) (*models.Todo, error) {
+	var done models.Todo
+	if err := tx.Preload("Tags").Where("id = ?", id).First(&done).Error; err != nil {
+		return nil, err
+	}
+	rule, err := parseRecurrence(done.RecurrenceRule)
+	if err != nil {
+		return nil, err
+	}
+
+	from := s.now()
+	if done.DueDate != nil {
+		from = *done.DueDate
+	}
+	due := rule.next(from)
+	next := &models.Todo{
+		Description:    done.Description,
+		DueDate:        &due,
+		Priority:       done.Priority,
+		ParentID:       done.ParentID,
+		RecurrenceRule: done.RecurrenceRule,
+		Tags:           done.Tags,
+	}
+	if err := assignPositions(tx, next); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(next).Error; err != nil {
+		return nil, fmt.Errorf("create next occurrence: %w", err)
+	}
+	return next, nil
+}
+
+// setLockTimeout bounds how long the rest of tx may wait on row locks
+func (s *todoService) setLockTimeout(tx *gorm.DB) error {
+	if s.lockWait <= 0 {
+		return nil
+	}
+	if err := tx.Exec(fmt.Sprintf("SET LOCAL lock_timeout = %d", s.lockWait.Milliseconds())).Error; err != nil {
+		return fmt.Errorf("set lock timeout: %w", err)
+	}
+	return nil
 }
 
-// Delete deletes a todo item
+// isLockTimeout reports whether err is Postgres giving up on a contended lock
+func isLockTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == lockNotAvailable
+}
+
+// BatchUpdate applies a single change set to every listed todo in one transaction
+// IDs that are malformed or missing are reported as failures; the rest are still updated
+func (s *todoService) BatchUpdate(ctx context.Context, req *todov1.BatchUpdateTodosRequest) (*BatchUpdateResult, error) {
+	if len(req.Ids) == 0 {
+		return nil, fmt.Errorf("batch update todos: no ids: %w", ErrInvalidInput)
+	}
+	if len(req.Ids) > MaxBatchSize {
+		return nil, fmt.Errorf("batch update todos: more than %d ids: %w", MaxBatchSize, ErrInvalidInput)
+	}
+	if req.Update == nil {
+		return nil, fmt.Errorf("batch update todos: missing update: %w", ErrInvalidInput)
+	}
+
+	// Validate the shared change set once
+	changes, err := s.newChangeSet(req.Update)
+	if err != nil {
+		return nil, fmt.Errorf("batch update todos: %w", err)
+	}
+	if len(changes.updates) == 0 && !changes.replaceTags {
+		return nil, fmt.Errorf("batch update todos: empty update: %w", ErrInvalidInput)
+	}
+
+	result := &BatchUpdateResult{}
+	index := make(map[uuid.UUID]int, len(req.Ids))
+	for i, raw := range req.Ids {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			result.Failures = append(result.Failures, &BatchItemError{Index: i, Err: ErrInvalidInput})
+			continue
+		}
+		if _, dup := index[id]; !dup {
+			index[id] = i
+		}
+	}
+	ids := make([]uuid.UUID, 0, len(index))
+	for id := range index {
+		ids = append(ids, id)
+	}
+
+	var updated []*todov1.Todo
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var found []uuid.UUID
+		if err := tx.Model(&models.Todo{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+			return err
+		}
+		for _, id := range found {
+			delete(index, id)
+		}
+		if len(found) == 0 {
+			return nil
+		}
+
+		if parentID, ok := changes.updates["parent_id"].(*uuid.UUID); ok && parentID != nil {
+			for _, id := range found {
+				if err := checkParent(tx, id, *parentID); err != nil {
+					return err
+				}
+			}
+		}
+		// As in Update; subtasks completed by this same batch don't count as open
+		completing, _ := changes.updates["completed"].(bool)
+		if completing && s.subtaskGuard && !req.Update.Force {
+			var open int64
+			if err := tx.Model(&models.Todo{}).Where("parent_id IN ? AND completed = ? AND id NOT IN ?", found, false, found).Count(&open).Error; err != nil {
+				return err
+			}
+			if open > 0 {
+				return fmt.Errorf("complete todos with %d open subtasks: %w", open, ErrSubtasksIncomplete)
+			}
+		}
+		// Only todos this batch moves to completed recur; already-completed ones did so before
+		var opened []uuid.UUID
+		if completing {
+			if err := tx.Model(&models.Todo{}).Where("id IN ? AND completed = ?", found, false).Pluck("id", &opened).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes.updates) > 0 {
+			res := tx.Model(&models.Todo{}).Where("id IN ?", found).Updates(changes.updates)
+			if res.Error != nil {
+				return res.Error
+			}
+		}
+		if changes.replaceTags {
+			tags, err := resolveTags(tx, changes.tags)
+			if err != nil {
+				return err
+			}
+			for _, id := range found {
+				if err := tx.Model(&models.Todo{ID: id}).Association("Tags").Replace(tags); err != nil {
+					return err
+				}
+			}
+		}
+		result.Updated = int64(len(found))
+
+		var reloaded []models.Todo
+		if err := tx.Preload("Tags").Where("id IN ?", found).Order("position, id").Find(&reloaded).Error; err != nil {
+			return err
+		}
+		for i := range reloaded {
+			todo := toProto(&reloaded[i])
+			if err := s.enqueue(ctx, tx, EventUpdated, todo); err != nil {
+				return err
+			}
+			updated = append(updated, todo)
+		}
+
+		if len(opened) == 0 {
+			return nil
+		}
+		// The rule as written by the update decides, as in Update
+		var recurring []uuid.UUID
+		if err := tx.Model(&models.Todo{}).Where("id IN ? AND recurrence_rule <> ''", opened).Order("position, id").Pluck("id", &recurring).Error; err != nil {
+			return err
+		}
+		for _, id := range recurring {
+			nextTodo, err := s.createNextOccurrence(tx, id)
+			if err != nil {
+				return err
+			}
+			next := toProto(nextTodo)
+			if err := s.enqueue(ctx, tx, EventCreated, next); err != nil {
+				return err
+			}
+			result.NextOccurrences = append(result.NextOccurrences, next)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("batch update todos in database: %w", err)
+	}
+	for _, todo := range updated {
+		s.events.publish(ctx, EventUpdated, todo)
+	}
+	for _, todo := range result.NextOccurrences {
+		s.events.publish(ctx, EventCreated, todo)
+	}
+
+	// Whatever is left in index was not found
+	for _, i := range index {
+		result.Failures = append(result.Failures, &BatchItemError{Index: i, Err: ErrTodoNotFound})
+	}
+	sort.Slice(result.Failures, func(a, b int) bool {
+		return result.Failures[a].Index < result.Failures[b].Index
+	})
+
+	return result, nil
+}
+
+// Delete moves a todo item to the trash; it can be brought back with Restore
 func (s *todoService) Delete(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
 	// Parse UUID
 	id, err := uuid.Parse(req.Id)
@@ -198,29 +1472,867 @@ func (s *todoService) Delete(ctx context.Context, req *todov1.DeleteTodoRequest)
 		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
 	}
 
-	// Delete from database
-	result := s.db.WithContext(ctx).Where("id = ?", id).Delete(&models.Todo{})
-	if result.Error != nil {
-		return nil, fmt.Errorf("delete todo %s: %w", req.Id, result.Error)
+	if req.DryRun {
+		return s.deleteDryRun(ctx, id, req.Id)
 	}
 
-	// Check if todo existed
-	if result.RowsAffected == 0 {
-		return nil, fmt.Errorf("delete todo %s: %w", req.Id, ErrTodoNotFound)
+	var deleted []uuid.UUID
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = s.deleteSubtrees(ctx, tx, []uuid.UUID{id})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("delete todo %s: %w", req.Id, err)
 	}
 
+	s.undo.record(requestScopeOf(ctx), []string{req.Id}, s.now())
+	s.publishDeleted(ctx, deleted)
 	return &todov1.DeleteTodoResponse{}, nil
 }
 
+// DeleteCompleted moves every completed todo to the trash, subtasks included, like Delete
+// One Undo brings them all back. Returns the number of todos removed
+func (s *todoService) DeleteCompleted(ctx context.Context) (int64, error) {
+	var completed, deleted []uuid.UUID
+	err := s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Todo{}).Where("completed = ?", true).Pluck("id", &completed).Error; err != nil {
+			return err
+		}
+		if len(completed) == 0 {
+			return nil
+		}
+		var err error
+		deleted, err = s.deleteSubtrees(ctx, tx, completed)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("delete completed todos: %w", err)
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+
+	undo := make([]string, len(completed))
+	for i, id := range completed {
+		undo[i] = id.String()
+	}
+	s.undo.record(requestScopeOf(ctx), undo, s.now())
+	s.publishDeleted(ctx, deleted)
+	return int64(len(deleted)), nil
+}
+
+// deleteSubtrees moves roots and every todo below them to the trash inside tx, with an
+// outbox event each. One statement gives them all the same deleted_at, which is how
+// Restore finds the subtasks to bring back with their parent
+// Returns the IDs removed, roots first; ErrTodoNotFound when none of them exist
+func (s *todoService) deleteSubtrees(ctx context.Context, tx *gorm.DB, roots []uuid.UUID) ([]uuid.UUID, error) {
+	below, err := subtree(tx, roots)
+	if err != nil {
+		return nil, err
+	}
+	// A root may sit below another root; delete and report it once
+	seen := make(map[uuid.UUID]bool, len(roots)+len(below))
+	var ids []uuid.UUID
+	for _, id := range append(slices.Clone(roots), below...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	result := tx.Where("id IN ?", ids).Delete(&models.Todo{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTodoNotFound
+	}
+	for _, id := range ids {
+		if err := s.enqueue(ctx, tx, EventDeleted, &todov1.Todo{Id: id.String()}); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// publishDeleted tells subscribers the todos are gone
+func (s *todoService) publishDeleted(ctx context.Context, ids []uuid.UUID) {
+	for _, id := range ids {
+		s.events.publish(ctx, EventDeleted, &todov1.Todo{Id: id.String()})
+	}
+}
+
+// deleteDryRun reports the todo and its dependents without deleting anything
+func (s *todoService) deleteDryRun(ctx context.Context, id uuid.UUID, rawID string) (*todov1.DeleteTodoResponse, error) {
+	var todo models.Todo
+	if err := s.conn(ctx).Preload("Tags").Where("id = ?", id).First(&todo).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("delete todo %s: %w", rawID, ErrTodoNotFound)
+		}
+		return nil, fmt.Errorf("query todo %s: %w", rawID, err)
+	}
+	subtasks, err := subtree(s.conn(ctx), []uuid.UUID{id})
+	if err != nil {
+		return nil, fmt.Errorf("query subtasks of %s: %w", rawID, err)
+	}
+
+	return &todov1.DeleteTodoResponse{
+		DryRun: true,
+		Todo:   toProto(&todo),
+		Dependents: map[string]int32{
+			"tags":     int32(len(todo.Tags)),
+			"subtasks": int32(len(subtasks)),
+		},
+	}, nil
+}
+
+// Restore brings a soft-deleted todo back out of the trash
+func (s *todoService) Restore(ctx context.Context, req *todov1.RestoreTodoRequest) (*todov1.Todo, error) {
+	// Parse UUID
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
+	}
+
+	var todo models.Todo
+	if err := s.conn(ctx).Unscoped().Where("id = ?", id).First(&todo).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("restore todo %s: %w", req.Id, ErrTodoNotFound)
+		}
+		return nil, fmt.Errorf("query todo %s: %w", req.Id, err)
+	}
+	if !todo.DeletedAt.Valid {
+		return nil, fmt.Errorf("restore todo %s: %w", req.Id, ErrTodoNotDeleted)
+	}
+
+	// Subtasks deleted along with the todo come back with it; ones deleted on their own stay in the trash
+	deletedAt := todo.DeletedAt.Time
+	var restored []*todov1.Todo
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		// Guard on deleted_at so a concurrent restore only succeeds once
+		result := tx.Unscoped().Model(&todo).
+			Where("deleted_at IS NOT NULL").
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTodoNotDeleted
+		}
+
+		subtasks, err := subtree(tx.Unscoped().Where("deleted_at = ?", deletedAt), []uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+		if len(subtasks) > 0 {
+			if err := tx.Unscoped().Model(&models.Todo{}).Where("id IN ?", subtasks).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		// Receivers saw the todos deleted, so they come back as created
+		var back []models.Todo
+		if err := tx.Preload("Tags").Where("id IN ?", append(subtasks, id)).Order("position, id").Find(&back).Error; err != nil {
+			return err
+		}
+		for i := range back {
+			todo := toProto(&back[i])
+			if err := s.enqueue(ctx, tx, EventCreated, todo); err != nil {
+				return err
+			}
+			restored = append(restored, todo)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("restore todo %s: %w", req.Id, err)
+	}
+
+	for _, todo := range restored {
+		s.events.publish(ctx, EventCreated, todo)
+	}
+	return s.Get(ctx, &todov1.GetTodoRequest{Id: req.Id})
+}
+
+// Undo reverts the most recent delete if it happened within UndoWindow: the deleted todo,
+// or every todo a DeleteCompleted removed, comes back; the first one restored is returned
+// Each delete can be undone once; todos already restored or gone count as nothing to undo
+func (s *todoService) Undo(ctx context.Context) (*todov1.Todo, error) {
+	scope := requestScopeOf(ctx)
+	ids, ok := s.undo.last(scope, s.now())
+	if !ok {
+		return nil, fmt.Errorf("undo delete: %w", ErrNothingToUndo)
+	}
+
+	var first *todov1.Todo
+	for _, id := range ids {
+		todo, err := s.Restore(ctx, &todov1.RestoreTodoRequest{Id: id})
+		if errors.Is(err, ErrTodoNotFound) || errors.Is(err, ErrTodoNotDeleted) {
+			// Restored already, e.g. along with a parent earlier in ids
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("undo delete of %s: %w", id, err)
+		}
+		if first == nil {
+			first = todo
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("undo delete of %s: %w", strings.Join(ids, ", "), ErrNothingToUndo)
+	}
+	s.undo.forget(scope, ids)
+	return first, nil
+}
+
+// Archive hides a todo from List without deleting it
+// Archiving an archived todo changes nothing
+func (s *todoService) Archive(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error) {
+	return s.setArchived(ctx, req.Id, true)
+}
+
+// Unarchive returns an archived todo to the default List view
+// Unarchiving a todo that is not archived changes nothing
+func (s *todoService) Unarchive(ctx context.Context, req *todov1.ArchiveTodoRequest) (*todov1.Todo, error) {
+	return s.setArchived(ctx, req.Id, false)
+}
+
 // Helper functions
 
+// setArchived moves a todo in or out of the archive
+func (s *todoService) setArchived(ctx context.Context, rawID string, archived bool) (*todov1.Todo, error) {
+	op := "archive"
+	if !archived {
+		op = "unarchive"
+	}
+
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, fmt.Errorf("parse todo ID: %w", ErrInvalidInput)
+	}
+
+	var todo models.Todo
+	if err := s.conn(ctx).Preload("Tags").Where("id = ?", id).First(&todo).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("%s todo %s: %w", op, rawID, ErrTodoNotFound)
+		}
+		return nil, fmt.Errorf("query todo %s: %w", rawID, err)
+	}
+	if todo.Archived == archived {
+		return toProto(&todo), nil
+	}
+
+	var updated *todov1.Todo
+	err = s.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&todo).Update("archived", archived).Error; err != nil {
+			return err
+		}
+		var reloaded models.Todo
+		if err := tx.Preload("Tags").Where("id = ?", id).First(&reloaded).Error; err != nil {
+			return err
+		}
+		updated = toProto(&reloaded)
+		return s.enqueue(ctx, tx, EventUpdated, updated)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s todo %s: %w", op, rawID, err)
+	}
+	s.events.publish(ctx, EventUpdated, updated)
+	return updated, nil
+}
+
+// Ping checks that the database is reachable
+func (s *todoService) Ping(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return fmt.Errorf("ping database: %w", err)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping database: %w", err)
+	}
+	return nil
+}
+
+// Subscribe streams create, update and delete events for the caller's tenant
+// The channel closes when cancel is called or ctx is done; slow readers miss events
+func (s *todoService) Subscribe(ctx context.Context) (<-chan *todov1.TodoEvent, func()) {
+	return s.events.subscribe(ctx)
+}
+
+// CloseSubscriptions closes every event stream, e.g. on shutdown, so streaming clients
+// disconnect instead of holding the server open; later Subscribe calls get a closed channel
+// Returns how many streams were closed
+func (s *todoService) CloseSubscriptions() int {
+	return s.events.closeAll()
+}
+
+// newTodo validates a create request and builds the model to insert
+func (s *todoService) newTodo(req *todov1.CreateTodoRequest) (*models.Todo, error) {
+	desc, err := s.validateDescription(req.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	var dueDate *time.Time
+	if req.DueDate != nil {
+		if dueDate, err = parseDueDate(*req.DueDate); err != nil {
+			return nil, err
+		}
+	}
+
+	priority := models.PriorityMedium
+	if req.Priority != todov1.Priority_PRIORITY_UNSPECIFIED {
+		if priority, err = priorityToModel(req.Priority); err != nil {
+			return nil, err
+		}
+	}
+
+	var rule string
+	if req.RecurrenceRule != "" {
+		r, err := parseRecurrence(req.RecurrenceRule)
+		if err != nil {
+			return nil, err
+		}
+		rule = r.String()
+	}
+
+	var parentID *uuid.UUID
+	if req.ParentId != nil {
+		id, err := uuid.Parse(*req.ParentId)
+		if err != nil {
+			return nil, fmt.Errorf("parse parent ID: %w", ErrInvalidInput)
+		}
+		parentID = &id
+	}
+
+	return &models.Todo{
+		Description:    desc,
+		Completed:      false,
+		DueDate:        dueDate,
+		Priority:       priority,
+		ParentID:       parentID,
+		RecurrenceRule: rule,
+	}, nil
+}
+
+// likeEscaper escapes LIKE metacharacters so they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes s for use inside a LIKE pattern
+// Backslash is PostgreSQL's default LIKE escape character
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// pageCursor is the position after which the next cursor page starts
+type pageCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// encodePageToken builds an opaque page token positioned after todo
+func encodePageToken(todo *models.Todo) string {
+	data, _ := json.Marshal(pageCursor{CreatedAt: todo.CreatedAt, ID: todo.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken parses a token produced by encodePageToken
+func decodePageToken(token string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed page token: %w", ErrInvalidInput)
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, fmt.Errorf("malformed page token: %w", ErrInvalidInput)
+	}
+	return &cursor, nil
+}
+
+// changeSet is a validated update that can be applied to one or more todos
+type changeSet struct {
+	updates     map[string]interface{}
+	replaceTags bool
+	tags        []string // Normalized and sorted
+}
+
+// newChangeSet validates every field of an update request without touching the database
+func (s *todoService) newChangeSet(req *todov1.UpdateTodoRequest) (*changeSet, error) {
+	updates := make(map[string]interface{})
+
+	if req.Description != nil {
+		desc, err := s.validateDescription(*req.Description)
+		if err != nil {
+			return nil, err
+		}
+		updates["description"] = desc
+	}
+
+	if req.Completed != nil {
+		updates["completed"] = *req.Completed
+	}
+
+	if req.DueDate != nil {
+		// Empty string clears the due date
+		var dueDate *time.Time
+		if *req.DueDate != "" {
+			var err error
+			if dueDate, err = parseDueDate(*req.DueDate); err != nil {
+				return nil, err
+			}
+		}
+		updates["due_date"] = dueDate
+	}
+
+	if req.Priority != nil {
+		priority, err := priorityToModel(*req.Priority)
+		if err != nil {
+			return nil, err
+		}
+		updates["priority"] = priority
+	}
+
+	if req.ParentId != nil {
+		// Empty string makes the todo top-level
+		var parentID *uuid.UUID
+		if *req.ParentId != "" {
+			id, err := uuid.Parse(*req.ParentId)
+			if err != nil {
+				return nil, fmt.Errorf("parse parent ID: %w", ErrInvalidInput)
+			}
+			parentID = &id
+		}
+		updates["parent_id"] = parentID
+	}
+
+	changes := &changeSet{
+		updates:     updates,
+		replaceTags: req.ClearTags || len(req.Tags) > 0,
+	}
+	if len(req.Tags) > 0 {
+		tags, err := normalizeTags(req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(tags)
+		changes.tags = tags
+	}
+	return changes, nil
+}
+
+// dropUnchanged removes updates that would leave a column at its current value
+func dropUnchanged(todo *models.Todo, updates map[string]interface{}) {
+	for col, val := range updates {
+		var same bool
+		switch col {
+		case "description":
+			same = val.(string) == todo.Description
+		case "completed":
+			same = val.(bool) == todo.Completed
+		case "priority":
+			same = val.(string) == todo.Priority
+		case "due_date":
+			due := val.(*time.Time)
+			same = (due == nil && todo.DueDate == nil) ||
+				(due != nil && todo.DueDate != nil && due.Equal(*todo.DueDate))
+		case "parent_id":
+			parent := val.(*uuid.UUID)
+			same = (parent == nil && todo.ParentID == nil) ||
+				(parent != nil && todo.ParentID != nil && *parent == *todo.ParentID)
+		}
+		if same {
+			delete(updates, col)
+		}
+	}
+}
+
+// validateDescription trims a description and checks it is non-empty and within the length limits
+// Trimming covers all unicode whitespace, so e.g. a lone U+3000 counts as empty
+func (s *todoService) validateDescription(raw string) (string, error) {
+	desc := strings.TrimFunc(raw, unicode.IsSpace)
+	if desc == "" {
+		return "", ErrEmptyDescription
+	}
+	if utf8.RuneCountInString(desc) < s.minDescLen {
+		return "", fmt.Errorf("description shorter than %d chars: %w", s.minDescLen, ErrDescriptionTooShort)
+	}
+	if utf8.RuneCountInString(desc) > s.maxDescLen {
+		return "", fmt.Errorf("description too long (max %d chars): %w", s.maxDescLen, ErrInvalidInput)
+	}
+	// Raw control characters such as NUL or ESC garble terminals and logs
+	for i, r := range desc {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' {
+			return "", fmt.Errorf("description has control character %U at byte %d: %w", r, i, ErrInvalidCharacters)
+		}
+	}
+	return desc, nil
+}
+
+// parseSortField validates List's sort_by and order, defaulting to created_at descending
+// Only fields in sortColumns are accepted, so the result is safe to use as a column name
+func parseSortField(sortBy, order string) (string, bool, error) {
+	if sortBy == "" {
+		sortBy = SortByCreatedAt
+	}
+	if _, ok := sortColumns[sortBy]; !ok {
+		return "", false, fmt.Errorf("unknown sort_by %q: %w", sortBy, ErrInvalidInput)
+	}
+	switch strings.ToLower(order) {
+	case "":
+		return sortBy, sortBy != SortByPosition, nil
+	case OrderDesc:
+		return sortBy, true, nil
+	case OrderAsc:
+		return sortBy, false, nil
+	}
+	return "", false, fmt.Errorf("unknown order %q: %w", order, ErrInvalidInput)
+}
+
+// defaultCompleted maps a default list filter to the completed value it selects, nil for all
+func defaultCompleted(filter string) *bool {
+	var completed bool
+	switch filter {
+	case ListFilterActive:
+		completed = false
+	case ListFilterCompleted:
+		completed = true
+	default:
+		return nil
+	}
+	return &completed
+}
+
+// maxDescriptionLength returns the longest description allowed under mode
+func maxDescriptionLength(mode string) int {
+	if mode == LimitExclusive {
+		return MaxDescriptionLength - 1
+	}
+	return MaxDescriptionLength
+}
+
+// parseDueDate parses an RFC3339 due date
+func parseDueDate(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("due date %q is not RFC3339: %w", value, ErrInvalidInput)
+	}
+	return &t, nil
+}
+
+// parseCreatedRange parses the RFC3339 bounds of a created_at range, either of which may be empty
+// Both bounds are inclusive, so equal bounds select todos created at exactly that instant
+func parseCreatedRange(afterValue, beforeValue string) (after, before *time.Time, err error) {
+	if afterValue != "" {
+		t, err := time.Parse(time.RFC3339, afterValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("created_after %q is not RFC3339: %w", afterValue, ErrInvalidInput)
+		}
+		after = &t
+	}
+	if beforeValue != "" {
+		t, err := time.Parse(time.RFC3339, beforeValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("created_before %q is not RFC3339: %w", beforeValue, ErrInvalidInput)
+		}
+		before = &t
+	}
+	if after != nil && before != nil && after.After(*before) {
+		return nil, nil, fmt.Errorf("created_after %s is later than created_before %s: %w", afterValue, beforeValue, ErrInvalidInput)
+	}
+	return after, before, nil
+}
+
+// priorityToModel maps a protobuf priority to its stored value, rejecting unknown levels
+func priorityToModel(p todov1.Priority) (string, error) {
+	switch p {
+	case todov1.Priority_PRIORITY_LOW:
+		return models.PriorityLow, nil
+	case todov1.Priority_PRIORITY_MEDIUM:
+		return models.PriorityMedium, nil
+	case todov1.Priority_PRIORITY_HIGH:
+		return models.PriorityHigh, nil
+	default:
+		return "", fmt.Errorf("unknown priority %d: %w", p, ErrInvalidInput)
+	}
+}
+
+// priorityToProto maps a stored priority back to its protobuf enum
+func priorityToProto(p string) todov1.Priority {
+	switch p {
+	case models.PriorityLow:
+		return todov1.Priority_PRIORITY_LOW
+	case models.PriorityHigh:
+		return todov1.Priority_PRIORITY_HIGH
+	default:
+		return todov1.Priority_PRIORITY_MEDIUM
+	}
+}
+
+// insertTodo saves a new todo and attaches its tags within tx
+func insertTodo(tx *gorm.DB, todo *models.Todo, tagNames []string) error {
+	tags, err := resolveTags(tx, tagNames)
+	if err != nil {
+		return err
+	}
+	todo.Tags = tags
+	if todo.ParentID != nil {
+		if err := checkParent(tx, todo.ID, *todo.ParentID); err != nil {
+			return err
+		}
+	}
+	if err := assignPositions(tx, todo); err != nil {
+		return err
+	}
+	return tx.Create(todo).Error
+}
+
+// attachTags sets the tags named in tagNames[i] on todos[i], resolving every name in one pass
+func attachTags(tx *gorm.DB, todos []*models.Todo, tagNames [][]string) error {
+	var all []string
+	for _, names := range tagNames {
+		all = append(all, names...)
+	}
+	tags, err := resolveTags(tx, all)
+	if err != nil || len(tags) == 0 {
+		return err
+	}
+	for i, todo := range todos {
+		names, err := normalizeTags(tagNames[i])
+		if err != nil {
+			return err
+		}
+		wanted := toSet(names)
+		for _, tag := range tags {
+			if wanted[tag.Name] {
+				todo.Tags = append(todo.Tags, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// checkParent verifies that parentID names a live todo that id may be placed under,
+// i.e. neither id itself nor one of its subtasks, so subtasks always form a tree
+func checkParent(tx *gorm.DB, id, parentID uuid.UUID) error {
+	for cur := &parentID; cur != nil; {
+		if *cur == id {
+			return fmt.Errorf("todo %s cannot be a subtask of itself or its subtasks: %w", id, ErrInvalidInput)
+		}
+		var ancestor models.Todo
+		if err := tx.Select("id", "parent_id").Where("id = ?", *cur).First(&ancestor).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			if *cur == parentID {
+				return fmt.Errorf("parent todo %s not found: %w", parentID, ErrInvalidInput)
+			}
+			return nil
+		}
+		cur = ancestor.ParentID
+	}
+	return nil
+}
+
+// subtree returns the IDs of every todo below roots, level by level
+// scope selects which rows count, e.g. only those deleted together with the roots
+func subtree(scope *gorm.DB, roots []uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for level := roots; len(level) > 0; {
+		var children []uuid.UUID
+		if err := scope.Session(&gorm.Session{}).Model(&models.Todo{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, children...)
+		level = children
+	}
+	return ids, nil
+}
+
+// backdate sets historical timestamps on a todo about to be inserted
+// GORM's autoCreateTime/autoUpdateTime only fill zero timestamps on insert, so
+// non-zero values set here are written as-is. Without createdAt the todo keeps
+// insert-time timestamps; updatedAt defaults to createdAt
+func backdate(todo *models.Todo, createdAt, updatedAt *timestamppb.Timestamp, now time.Time) error {
+	if createdAt == nil {
+		if updatedAt != nil {
+			return fmt.Errorf("updated_at without created_at: %w", ErrInvalidInput)
+		}
+		return nil
+	}
+	if err := createdAt.CheckValid(); err != nil {
+		return fmt.Errorf("created_at: %v: %w", err, ErrInvalidInput)
+	}
+	if err := updatedAt.CheckValid(); updatedAt != nil && err != nil {
+		return fmt.Errorf("updated_at: %v: %w", err, ErrInvalidInput)
+	}
+
+	created := createdAt.AsTime()
+	updated := created
+	if updatedAt != nil {
+		updated = updatedAt.AsTime()
+	}
+	if created.After(now) || updated.After(now) {
+		return fmt.Errorf("timestamps cannot be in the future: %w", ErrInvalidInput)
+	}
+	if updated.Before(created) {
+		return fmt.Errorf("updated_at precedes created_at: %w", ErrInvalidInput)
+	}
+
+	todo.CreatedAt, todo.UpdatedAt = created, updated
+	return nil
+}
+
+// normalizeTags trims and lowercases tag names, dropping duplicates
+func normalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("tag name cannot be empty: %w", ErrInvalidInput)
+		}
+		if utf8.RuneCountInString(name) > 50 {
+			return nil, fmt.Errorf("tag %q too long (max 50 chars): %w", name, ErrInvalidInput)
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
+
+// resolveTags finds or creates the named tags inside tx
+func resolveTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	names, err := normalizeTags(names)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+
+	// Insert any missing tags; concurrent inserts of the same name are ignored
+	newTags := make([]models.Tag, len(names))
+	for i, name := range names {
+		newTags[i] = models.Tag{Name: name}
+	}
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&newTags).Error; err != nil {
+		return nil, fmt.Errorf("create tags: %w", err)
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("name IN ?", names).Order("name").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("load tags: %w", err)
+	}
+	return tags, nil
+}
+
+// toSet converts a list of names into a lookup set, preserving nil
+func toSet(names []string) map[string]bool {
+	if names == nil {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// allowed reports whether name is in set, treating a nil set as allowing everything
+func allowed(set map[string]bool, name string) bool {
+	return set == nil || set[name]
+}
+
 // toProto converts internal GORM model to public protobuf type
 func toProto(t *models.Todo) *todov1.Todo {
-	return &todov1.Todo{
-		Id:          t.ID.String(),
-		Description: t.Description,
-		Completed:   t.Completed,
-		CreatedAt:   timestamppb.New(t.CreatedAt),
-		UpdatedAt:   timestamppb.New(t.UpdatedAt),
-	}
-}
\ No newline at end of file
+	pb := &todov1.Todo{
+		Id:             t.ID.String(),
+		Description:    t.Description,
+		Completed:      t.Completed,
+		Archived:       t.Archived,
+		CreatedAt:      timestamppb.New(t.CreatedAt),
+		UpdatedAt:      timestamppb.New(t.UpdatedAt),
+		DueDate:        timestampOrNil(t.DueDate),
+		Priority:       priorityToProto(t.Priority),
+		Tags:           tagNames(t.Tags),
+		DeletedAt:      deletedAtOrNil(t.DeletedAt),
+		Version:        t.Version,
+		RecurrenceRule: t.RecurrenceRule,
+		Position:       t.Position,
+	}
+	if t.ParentID != nil {
+		pb.ParentId = t.ParentID.String()
+	}
+	pb.ContentHash = ContentHash(pb)
+	return pb
+}
+
+// ageBucket groups a todo's age for triage views
+func ageBucket(age time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case age < day:
+		return AgeBucketNew
+	case age < 7*day:
+		return AgeBucketRecent
+	case age <= 30*day:
+		return AgeBucketAging
+	}
+	return AgeBucketStale
+}
+
+// ContentHash returns a stable hash of a todo's content: description, completed,
+// due date, priority, tags, archived and recurrence rule. IDs, timestamps and
+// versions are left out, so the hash only changes when the content does
+func ContentHash(todo *todov1.Todo) string {
+	tags := slices.Clone(todo.Tags)
+	slices.Sort(tags)
+	due := ""
+	if todo.DueDate != nil {
+		due = strconv.FormatInt(todo.DueDate.AsTime().Truncate(time.Microsecond).UnixMicro(), 10)
+	}
+
+	h := sha256.New()
+	for _, part := range []string{
+		todo.Description,
+		strconv.FormatBool(todo.Completed),
+		due,
+		todo.Priority.String(),
+		strings.Join(tags, "\x00"),
+		strconv.FormatBool(todo.Archived),
+		todo.RecurrenceRule,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0xff})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// deletedAtOrNil converts a soft-delete marker, leaving live todos unset
+func deletedAtOrNil(d gorm.DeletedAt) *timestamppb.Timestamp {
+	if !d.Valid {
+		return nil
+	}
+	return timestamppb.New(d.Time)
+}
+
+// tagNames returns the sorted names of tags
+func tagNames(tags []models.Tag) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// timestampOrNil converts an optional time, keeping NULL as an unset field
+func timestampOrNil(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// UndoWindow is how long after a delete Undo can still bring the todo back
+const UndoWindow = 30 * time.Second
+
+// undoEntry is the most recent delete in one scope: the todos it removed directly
+type undoEntry struct {
+	ids       []string
+	deletedAt time.Time
+}
+
+// undoBuffer remembers the most recent delete of each tenant and user,
+// so one client's Undo never reverts another's delete
+type undoBuffer struct {
+	mu      sync.Mutex
+	entries map[requestScope]undoEntry
+}
+
+func newUndoBuffer() *undoBuffer {
+	return &undoBuffer{entries: make(map[requestScope]undoEntry)}
+}
+
+// record makes ids the todos the next Undo in scope restores
+func (b *undoBuffer) record(scope requestScope, ids []string, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[scope] = undoEntry{ids: ids, deletedAt: at}
+	b.prune(at)
+}
+
+// last returns the todos of scope's most recent delete if it happened within UndoWindow of now
+func (b *undoBuffer) last(scope requestScope, now time.Time) ([]string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[scope]
+	if !ok || now.Sub(entry.deletedAt) > UndoWindow {
+		return nil, false
+	}
+	return entry.ids, true
+}
+
+// forget clears scope's entry once ids are undone, unless a newer delete has replaced it
+func (b *undoBuffer) forget(scope requestScope, ids []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if slices.Equal(b.entries[scope].ids, ids) {
+		delete(b.entries, scope)
+	}
+}
+
+// prune drops entries past UndoWindow so scopes that stop deleting don't accumulate
+// The caller holds b.mu
+func (b *undoBuffer) prune(now time.Time) {
+	for scope, entry := range b.entries {
+		if now.Sub(entry.deletedAt) > UndoWindow {
+			delete(b.entries, scope)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package testutil
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/testcontainers/testcontainers-go"
@@ -69,4 +70,30 @@ func TruncateTables(db *gorm.DB, tables ...string) {
 	for i := len(tables) - 1; i >= 0; i-- {
 		db.Exec(fmt.Sprintf("TRUNCATE TABLE %s CASCADE", tables[i]))
 	}
-}
\ No newline at end of file
+}
+
+// CountInserts registers a callback that counts every INSERT statement issued through db
+// Returns a pointer to the running count
+func CountInserts(t *testing.T, db *gorm.DB) *atomic.Int64 {
+	var count atomic.Int64
+	name := "testutil:count_inserts"
+	if err := db.Callback().Create().Before("gorm:create").Register(name, func(*gorm.DB) {
+		count.Add(1)
+	}); err != nil {
+		t.Fatalf("Failed to register insert counter: %v", err)
+	}
+	return &count
+}
+
+// CountQueries registers a callback that counts every SELECT issued through db
+// Returns a pointer to the running count
+func CountQueries(t *testing.T, db *gorm.DB) *atomic.Int64 {
+	var count atomic.Int64
+	name := "testutil:count_queries"
+	if err := db.Callback().Query().Before("gorm:query").Register(name, func(*gorm.DB) {
+		count.Add(1)
+	}); err != nil {
+		t.Fatalf("Failed to register query counter: %v", err)
+	}
+	return &count
+}